@@ -1,24 +1,116 @@
 package sly
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChanBroadcastPolicy defines what the broadcaster does
+// when a sink isn't ready to receive.
+type ChanBroadcastPolicy int
+
+const (
+	// ChanBroadcastDisconnect closes and deletes the sink.
+	ChanBroadcastDisconnect ChanBroadcastPolicy = iota
+	// ChanBroadcastDropNewest drops the value the sink isn't ready for.
+	ChanBroadcastDropNewest
+	// ChanBroadcastDropOldest buffers the values in a ring buffer,
+	// dropping the oldest one on overflow. The buffer is drained into
+	// the sink by a separate goroutine.
+	ChanBroadcastDropOldest
+	// ChanBroadcastBlock blocks the whole broadcast until the sink is
+	// ready or the timeout expires, dropping the value on expiry.
+	// Deleting the sinks is still possible meanwhile.
+	ChanBroadcastBlock
+)
 
 type (
+	// ChanBroadcastSubOptions are used to register a sink, see AddOptions.
+	//
+	//  Policy: What to do when the sink isn't ready, see ChanBroadcastPolicy.
+	//  Buffer: Ring buffer capacity for ChanBroadcastDropOldest. If 0, then 1.
+	//  Timeout: Max wait for ChanBroadcastBlock. If 0, then unlimited.
+	//  Clock: Time source for the Timeout. If nil, then ClockReal.
+	//  OnDrop: Called with every value the sink has missed, see Dropped.
+	//  OnClose: Called right before the broadcaster closes the sink, see AddOptions.
+	//  Filter: Only the values it returns true for are sent. If nil, then all.
+	//  Map: Transforms the filtered values before sending. If nil, then identity.
+	//
+	// The callbacks are called on the broadcaster goroutine, or on the
+	// ring buffer one for ChanBroadcastDropOldest, and must not block.
+	// Filter and Map are always called on the broadcaster goroutine,
+	// OnDrop receives the mapped values.
+	ChanBroadcastSubOptions[T any] struct {
+		Policy  ChanBroadcastPolicy
+		Buffer  uint
+		Timeout time.Duration
+		Clock   Clock
+		OnDrop  func(value T)
+		OnClose func(err error)
+		Filter  func(value T) bool
+		Map     func(value T) T
+	}
+
 	chanBroadcastSub[T any] struct {
 		ctx  context.Context
 		sink chan<- T
+		opts ChanBroadcastSubOptions[T]
+		// Nil unless the policy is ChanBroadcastDropOldest.
+		ring *chanBroadcastRing[T]
+	}
+
+	// chanBroadcastRing is a ring buffer drained into the sink by
+	// the forwarder goroutine, see chanBroadcastSub.forward.
+	chanBroadcastRing[T any] struct {
+		mu     sync.Mutex
+		values []T
+		head   int
+		len    int
+		// Signaled when a value is pushed.
+		ready chan struct{}
+		// Closed to stop the forwarder. The fields below are set
+		// before that and are safe to read once it's closed.
+		stop chan struct{}
+		// The forwarder leaves the sink open if set, the subscription
+		// has been replaced.
+		detach bool
+		// The forwarder delivers the buffered values before closing
+		// the sink if set, the source has been closed.
+		flush bool
+		// Reported to OnClose.
+		err error
+		// Closed once the forwarder has exited.
+		done chan struct{}
 	}
 
 	// ChanBroadcast allows for broadcasting values from the source
 	// channel to multiple sink channels. Ensure that sink channels
-	// have adequate capacity to keep up with the broadcasts.
+	// have adequate capacity to keep up with the broadcasts, or pick
+	// a suitable ChanBroadcastPolicy.
+	//
+	// The sinks registered via Add are closed by the broadcaster, so
+	// the caller must not close them. Prefer Subscribe, which creates
+	// the sink itself.
 	ChanBroadcast[T any] struct {
+		// Atomic. Must stay the first field, as only that one is
+		// guaranteed to be 64-bit aligned on the 32-bit platforms.
+		dropped uint64
+
 		done   chan struct{}
 		accept context.Context
 		source <-chan T
 
 		add    chan chanBroadcastSub[T]
 		delete chan chan<- T
-		sinks  map[chan<- T]context.Context
+		sinks  map[chan<- T]chanBroadcastSub[T]
+
+		// Ring buffer of the last values for the new sinks,
+		// the oldest one is at replayHead once it's full.
+		replay     []T
+		replayHead int
 	}
 )
 
@@ -29,6 +121,20 @@ type (
 //	source: Channel to read from.
 //	nsinks: Initial capacity for the sinks map.
 func NewChanBroadcast[T any](accept context.Context, source <-chan T, nsinks uint) *ChanBroadcast[T] {
+	return NewChanBroadcastReplay(accept, source, nsinks, 0)
+}
+
+// NewChanBroadcastReplay creates a new ChanBroadcast, which replays the
+// last values to the new sinks before the live ones.
+//
+//	accept: Cancellation context. If nil, defaults to context.Background().
+//	source: Channel to read from.
+//	nsinks: Initial capacity for the sinks map.
+//	replay: Number of the last values to replay. If 1, then only the latest.
+//
+// The replayed values are subject to the sink options, so ensure that
+// the sink has capacity for them or pick a suitable ChanBroadcastPolicy.
+func NewChanBroadcastReplay[T any](accept context.Context, source <-chan T, nsinks, replay uint) *ChanBroadcast[T] {
 	if accept == nil {
 		accept = context.Background()
 	}
@@ -38,7 +144,9 @@ func NewChanBroadcast[T any](accept context.Context, source <-chan T, nsinks uin
 		source: source,
 		add:    make(chan chanBroadcastSub[T]),
 		delete: make(chan chan<- T),
-		sinks:  make(map[chan<- T]context.Context, nsinks),
+		sinks:  make(map[chan<- T]chanBroadcastSub[T], nsinks),
+
+		replay: make([]T, 0, replay),
 	}
 	go b.run()
 	return &b
@@ -46,27 +154,76 @@ func NewChanBroadcast[T any](accept context.Context, source <-chan T, nsinks uin
 
 // AddContext registers a new sink channel to receive broadcasts.
 //
+// This is a convenience function for AddOptions with the default
+// ChanBroadcastDisconnect policy.
+//
+// See AddOptions for more details.
+func (b *ChanBroadcast[T]) AddContext(broadcast context.Context, sink chan<- T) {
+	_ = b.AddOptions(broadcast, sink, ChanBroadcastSubOptions[T]{})
+}
+
+// AddOptions registers a new sink channel to receive broadcasts.
+//
 //	broadcast: Cancellation context. If nil, defaults to context.Background().
 //	sink: Sink channel.
+//	opts: See ChanBroadcastSubOptions.
+//
+// Returns an error if the options are invalid.
 //
 // If the broadcast is already canceled, the subscription is ignored.
 //
 // If the sink channel is already registered, the subscription is replaced.
-func (b *ChanBroadcast[T]) AddContext(broadcast context.Context, sink chan<- T) {
+// The values buffered for the replaced subscription are discarded.
+//
+// The broadcaster closes the sink once it's done with it, and reports
+// the reason to OnClose: ErrFull if the sink was disconnected for not
+// being ready, the broadcast or accept context error if either was
+// canceled, or ErrClosed if the sink was deleted or the source closed.
+// For ChanBroadcastDropOldest, the buffered values are delivered before
+// the sink is closed in the latter case, unless either context is done.
+// Otherwise, they are discarded, see Dropped.
+func (b *ChanBroadcast[T]) AddOptions(broadcast context.Context, sink chan<- T, opts ChanBroadcastSubOptions[T]) error {
+	_, err := b.addOptions(broadcast, sink, opts)
+	return err
+}
+
+// addOptions is AddOptions, which also reports whether
+// the sink has been registered.
+func (b *ChanBroadcast[T]) addOptions(
+	broadcast context.Context,
+	sink chan<- T,
+	opts ChanBroadcastSubOptions[T],
+) (bool, error) {
+	if opts.Policy < ChanBroadcastDisconnect || opts.Policy > ChanBroadcastBlock {
+		return false, fmt.Errorf("%w: unknown policy %d", ErrBadOptions, opts.Policy)
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = 1
+	}
+	if opts.Clock == nil {
+		opts.Clock = ClockReal{}
+	}
 	if broadcast == nil {
 		broadcast = context.Background()
 	}
+	// Select is random, the subscription might get through otherwise.
+	if broadcast.Err() != nil {
+		return false, nil
+	}
 
 	select {
 	case b.add <- chanBroadcastSub[T]{
 		ctx:  broadcast,
 		sink: sink,
+		opts: opts,
 	}:
+		return true, nil
 	case <-broadcast.Done():
 	// Using b.done here, instead of the b.accept.Done(),
 	// because of the source closure case.
 	case <-b.done:
 	}
+	return false, nil
 }
 
 // Add a new sink channel to receive broadcasts.
@@ -78,6 +235,76 @@ func (b *ChanBroadcast[T]) Add(sink chan<- T) {
 	b.AddContext(context.Background(), sink)
 }
 
+// Subscribe registers a new sink channel owned by the broadcaster.
+//
+// This is a convenience function for SubscribeOptions with the default
+// ChanBroadcastDisconnect policy.
+//
+// See SubscribeOptions for more details.
+func (b *ChanBroadcast[T]) Subscribe(broadcast context.Context, bufSize uint) (<-chan T, func()) {
+	sink, unsubscribe, _ := b.SubscribeOptions(broadcast, bufSize, ChanBroadcastSubOptions[T]{})
+	return sink, unsubscribe
+}
+
+// SubscribeOptions registers a new sink channel owned by the broadcaster.
+//
+//	broadcast: Cancellation context. If nil, defaults to context.Background().
+//	bufSize: Buffer size for the sink channel.
+//	opts: See ChanBroadcastSubOptions.
+//
+// Returns the sink channel and the function deleting it, or an error
+// if the options are invalid. The sink is closed once deleted, see
+// AddOptions, the buffered values are discarded then. It's closed right
+// away if the broadcast is already canceled or the broadcaster has finished.
+//
+// The delete function returns once the sink is closed, it doesn't need
+// to be read for that.
+func (b *ChanBroadcast[T]) SubscribeOptions(
+	broadcast context.Context,
+	bufSize uint,
+	opts ChanBroadcastSubOptions[T],
+) (<-chan T, func(), error) {
+	if broadcast == nil {
+		broadcast = context.Background()
+	}
+	// Canceled on delete, as the sink might be flushing
+	// after the source closure.
+	broadcast, cancel := context.WithCancel(broadcast)
+
+	closed := make(chan struct{})
+	onClose := opts.OnClose
+	opts.OnClose = func(err error) {
+		if onClose != nil {
+			onClose(err)
+		}
+		close(closed)
+	}
+
+	sink := make(chan T, bufSize)
+	added, err := b.addOptions(broadcast, sink, opts)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if !added {
+		reason := ErrClosed
+		if err := broadcast.Err(); err != nil {
+			reason = err
+		} else if err := b.accept.Err(); err != nil {
+			reason = err
+		}
+		opts.OnClose(reason)
+		close(sink)
+	}
+
+	unsubscribe := func() {
+		b.Delete(sink)
+		cancel()
+		<-closed
+	}
+	return sink, unsubscribe, nil
+}
+
 // DeleteContext deletes the sink from broadcasting queue.
 //
 //	delete: Cancellation context. If nil, defaults to context.Background().
@@ -136,10 +363,26 @@ func (b *ChanBroadcast[T]) Wait() {
 	_ = b.WaitContext(context.Background())
 }
 
+// Dropped returns the total number of values the sinks have missed:
+// dropped by the policies, see ChanBroadcastPolicy, or discarded as
+// the sink was disconnected, canceled, deleted or replaced. The values
+// filtered out and the ones broadcast after the sink was canceled
+// or gone don't count.
+func (b *ChanBroadcast[T]) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
 func (b *ChanBroadcast[T]) run() {
 	defer func() {
-		for sink := range b.sinks {
-			b.closeAndDeleteLF(sink)
+		reason := ErrClosed
+		if err := b.accept.Err(); err != nil {
+			reason = err
+		}
+		for sink, sub := range b.sinks {
+			if sub.ring != nil {
+				sub.ring.flush = true
+			}
+			b.closeAndDeleteLF(sink, reason)
 		}
 		close(b.done)
 	}()
@@ -151,21 +394,22 @@ func (b *ChanBroadcast[T]) run() {
 				return
 			}
 
-			for sink, ctx := range b.sinks {
-				select {
-				case sink <- value:
-				case <-ctx.Done():
-					b.closeAndDeleteLF(sink)
-				default:
-					b.closeAndDeleteLF(sink)
-				}
+			switch {
+			case len(b.replay) < cap(b.replay):
+				b.replay = append(b.replay, value)
+			case len(b.replay) > 0:
+				b.replay[b.replayHead] = value
+				b.replayHead = (b.replayHead + 1) % len(b.replay)
+			}
+			for _, sub := range b.sinks {
+				b.sendLF(sub, value)
 			}
 
 		case sub := <-b.add:
-			b.sinks[sub.sink] = sub.ctx
+			b.addLF(sub)
 
 		case sink := <-b.delete:
-			b.closeAndDeleteLF(sink)
+			b.closeAndDeleteLF(sink, ErrClosed)
 
 		case <-b.accept.Done():
 			return
@@ -173,12 +417,258 @@ func (b *ChanBroadcast[T]) run() {
 	}
 }
 
-func (b *ChanBroadcast[T]) closeAndDeleteLF(sink chan<- T) {
-	_, ok := b.sinks[sink]
-	if !ok {
+func (b *ChanBroadcast[T]) addLF(sub chanBroadcastSub[T]) {
+	// Making sure the replaced forwarder no longer sends to the sink.
+	old, replaced := b.sinks[sub.sink]
+	if replaced && old.ring != nil {
+		old.ring.detach = true
+		close(old.ring.stop)
+		<-old.ring.done
+	}
+
+	if sub.opts.Policy == ChanBroadcastDropOldest {
+		sub.ring = &chanBroadcastRing[T]{
+			values: make([]T, sub.opts.Buffer),
+			ready:  make(chan struct{}, 1),
+			stop:   make(chan struct{}),
+			done:   make(chan struct{}),
+		}
+		go b.forward(sub)
+	}
+	b.sinks[sub.sink] = sub
+
+	// The replaced sink has seen these already.
+	if replaced {
+		return
+	}
+	for i := range b.replay {
+		// The sink might get disconnected halfway.
+		if _, ok := b.sinks[sub.sink]; !ok {
+			return
+		}
+		b.sendLF(sub, b.replay[(b.replayHead+i)%len(b.replay)])
+	}
+}
+
+func (b *ChanBroadcast[T]) sendLF(sub chanBroadcastSub[T], value T) {
+	if err := sub.ctx.Err(); err != nil {
+		b.closeAndDeleteLF(sub.sink, err)
+		return
+	}
+
+	if sub.opts.Filter != nil && !sub.opts.Filter(value) {
+		return
+	}
+	if sub.opts.Map != nil {
+		value = sub.opts.Map(value)
+	}
+
+	if sub.ring != nil {
+		if evicted, ok := sub.ring.push(value); ok {
+			b.drop(sub, evicted)
+		}
+		return
+	}
+
+	select {
+	case sub.sink <- value:
 		return
+	default:
+	}
+
+	switch sub.opts.Policy {
+	case ChanBroadcastDropNewest:
+		b.drop(sub, value)
+
+	case ChanBroadcastBlock:
+		var timeout <-chan time.Time
+		if sub.opts.Timeout > 0 {
+			timer := sub.opts.Clock.NewTimer(sub.opts.Timeout)
+			defer timer.Stop()
+			timeout = timer.C()
+		}
+
+		for {
+			select {
+			case sub.sink <- value:
+			case <-sub.ctx.Done():
+				b.drop(sub, value)
+				b.closeAndDeleteLF(sub.sink, sub.ctx.Err())
+			case <-timeout:
+				b.drop(sub, value)
+			case <-b.accept.Done():
+				b.drop(sub, value)
+
+			// Otherwise, the sink being blocked on couldn't be deleted.
+			case sink := <-b.delete:
+				if sink != sub.sink {
+					b.closeAndDeleteLF(sink, ErrClosed)
+					continue
+				}
+				b.drop(sub, value)
+				b.closeAndDeleteLF(sink, ErrClosed)
+			}
+			return
+		}
+
+	default:
+		b.drop(sub, value)
+		b.closeAndDeleteLF(sub.sink, ErrFull)
 	}
+}
 
-	close(sink)
+func (b *ChanBroadcast[T]) drop(sub chanBroadcastSub[T], value T) {
+	atomic.AddUint64(&b.dropped, 1)
+	if sub.opts.OnDrop != nil {
+		sub.opts.OnDrop(value)
+	}
+}
+
+func (b *ChanBroadcast[T]) closeAndDeleteLF(sink chan<- T, err error) {
+	sub, ok := b.sinks[sink]
+	if !ok {
+		return
+	}
 	delete(b.sinks, sink)
+
+	// The forwarder closes the sink itself.
+	if sub.ring != nil {
+		sub.ring.err = err
+		close(sub.ring.stop)
+		return
+	}
+	sub.close(err)
+}
+
+func (s chanBroadcastSub[T]) close(err error) {
+	if s.opts.OnClose != nil {
+		s.opts.OnClose(err)
+	}
+	close(s.sink)
+}
+
+// forward drains the ring buffer into the sink until stopped.
+func (b *ChanBroadcast[T]) forward(sub chanBroadcastSub[T]) {
+	r := sub.ring
+	defer close(r.done)
+
+	// Nil once the broadcaster has stopped the forwarder
+	// for flushing the remaining values.
+	stop := r.stop
+	for {
+		value, ok := r.pop()
+		if !ok {
+			if stop == nil {
+				sub.close(r.err)
+				return
+			}
+
+			select {
+			case <-r.ready:
+			case <-stop:
+				if !r.flush {
+					b.abort(sub)
+					return
+				}
+				stop = nil
+			case <-b.accept.Done():
+				b.abort(sub)
+				return
+			}
+			continue
+		}
+
+		for sent := false; !sent; {
+			select {
+			case sub.sink <- value:
+				sent = true
+
+			case <-stop:
+				if !r.flush {
+					b.abort(sub, value)
+					return
+				}
+				stop = nil
+
+			case <-sub.ctx.Done():
+				b.abort(sub, value)
+				return
+
+			case <-b.accept.Done():
+				b.abort(sub, value)
+				return
+			}
+		}
+	}
+}
+
+// abort discards the ring buffer once the forwarder is stopped
+// without flushing or either context is done, then closes the sink
+// unless the subscription has been replaced.
+//
+//	sub: Subscription being forwarded.
+//	held: Values popped, but not delivered yet.
+func (b *ChanBroadcast[T]) abort(sub chanBroadcastSub[T], held ...T) {
+	// Waiting for the broadcaster to notice the cancellation,
+	// it might still be pushing onto the ring buffer.
+	<-sub.ring.stop
+	b.discard(sub, held...)
+	if !sub.ring.detach {
+		sub.close(sub.ring.err)
+	}
+}
+
+// discard drops the held values and the ring buffer contents.
+// The broadcaster must be done with the subscription.
+func (b *ChanBroadcast[T]) discard(sub chanBroadcastSub[T], held ...T) {
+	for _, value := range held {
+		b.drop(sub, value)
+	}
+	for {
+		value, ok := sub.ring.pop()
+		if !ok {
+			return
+		}
+		b.drop(sub, value)
+	}
+}
+
+// push pushes the value onto the ring buffer.
+//
+// Returns the evicted value, if the buffer was full.
+func (r *chanBroadcastRing[T]) push(value T) (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var evicted T
+	full := r.len == len(r.values)
+	if full {
+		evicted = r.values[r.head]
+		r.values[r.head] = value
+		r.head = (r.head + 1) % len(r.values)
+	} else {
+		r.values[(r.head+r.len)%len(r.values)] = value
+		r.len++
+	}
+
+	select {
+	case r.ready <- struct{}{}:
+	default:
+	}
+	return evicted, full
+}
+
+func (r *chanBroadcastRing[T]) pop() (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var value T
+	if r.len == 0 {
+		return value, false
+	}
+
+	value, r.values[r.head] = r.values[r.head], value
+	r.head = (r.head + 1) % len(r.values)
+	r.len--
+	return value, true
 }
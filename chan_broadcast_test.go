@@ -3,6 +3,7 @@ package sly
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
 	"time"
 )
@@ -22,9 +23,7 @@ func TestChanBroadcast(t *testing.T) {
 		// Must not panic.
 		b.Delete(sink)
 
-		// Giving the broadcast time to handle delete.
-		time.Sleep(10 * time.Millisecond)
-
+		// The broadcast handles the delete before reading the source again.
 		source <- 2
 		value, more := <-sink
 		assert.Equal(t, 0, value)
@@ -110,21 +109,487 @@ func TestChanBroadcast(t *testing.T) {
 		assert.False(t, more)
 	})
 
-	t.Run("broadcast cancel", func(t *testing.T) {
+	t.Run("sink overflow close reason", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		var reason error
+		sink := make(chan int)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			OnClose: func(err error) { reason = err },
+		}))
+
+		// Nobody is receiving, the second value is read once the first
+		// one has been handled.
+		source <- 1
+		source <- 2
+		_, more := <-sink
+		assert.False(t, more)
+		assert.ErrorIs(t, reason, ErrFull)
+		assert.Equal(t, uint64(1), b.Dropped())
+	})
+
+	t.Run("bad options", func(t *testing.T) {
+		b := NewChanBroadcast(nil, make(chan int), 0)
+		err := b.AddOptions(nil, make(chan int), ChanBroadcastSubOptions[int]{Policy: -1})
+		assert.ErrorIs(t, err, ErrBadOptions)
+	})
+
+	t.Run("policy drop newest", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		var dropped []int
+		sink := make(chan int, 2)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastDropNewest,
+			OnDrop: func(value int) { dropped = append(dropped, value) },
+		}))
+
+		for i := 1; i <= 4; i++ {
+			source <- i
+		}
+		close(source)
+		b.Wait()
+
+		assert.Equal(t, []int{1, 2}, chanBroadcastCollect(sink))
+		assert.Equal(t, []int{3, 4}, dropped)
+		assert.Equal(t, uint64(2), b.Dropped())
+	})
+
+	t.Run("policy drop oldest", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		var dropped []int
+		var reason error
+		sink := make(chan int)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			Policy:  ChanBroadcastDropOldest,
+			Buffer:  2,
+			OnDrop:  func(value int) { dropped = append(dropped, value) },
+			OnClose: func(err error) { reason = err },
+		}))
+
+		for i := 1; i <= 5; i++ {
+			source <- i
+		}
+		close(source)
+
+		// The buffered values are flushed before the sink is closed.
+		received := chanBroadcastCollect(sink)
+		assert.Equal(t, []int{4, 5}, received[len(received)-2:])
+		assert.Equal(t, 5, len(received)+len(dropped))
+		assert.Equal(t, uint64(len(dropped)), b.Dropped())
+		assert.ErrorIs(t, reason, ErrClosed)
+	})
+
+	t.Run("policy drop oldest replace", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		sink := make(chan int, 1)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastDropOldest,
+		}))
+		source <- 1
+		assert.Equal(t, 1, <-sink)
+
+		// Replacing must not close the sink.
+		b.Add(sink)
+		source <- 2
+		assert.Equal(t, 2, <-sink)
+	})
+
+	t.Run("policy drop oldest cancel", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		var reason error
 		ctx, cancel := context.WithCancel(context.TODO())
+		sink, buffered := make(chan int), make(chan struct{})
+		assert.NoError(t, b.AddOptions(ctx, sink, ChanBroadcastSubOptions[int]{
+			Policy:  ChanBroadcastDropOldest,
+			OnClose: func(err error) { reason = err },
+			Filter: func(value int) bool {
+				if value == 1 {
+					close(buffered)
+				}
+				return true
+			},
+		}))
+
+		source <- 1
+		<-buffered
+		cancel()
+		source <- 2
+
+		// Whatever is left in the buffer is discarded.
+		values := chanBroadcastCollect(sink)
+		assert.ErrorIs(t, reason, context.Canceled)
+		assert.Equal(t, uint64(1-len(values)), b.Dropped())
+	})
+
+	t.Run("policy drop oldest delete", func(t *testing.T) {
 		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		closed := make(chan error, 1)
+		sink := make(chan int)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			Policy:  ChanBroadcastDropOldest,
+			Buffer:  2,
+			OnClose: func(err error) { closed <- err },
+		}))
+
+		source <- 1
+		source <- 2
+		b.Delete(sink)
+
+		// Nobody is receiving, the buffered values are discarded.
+		assert.ErrorIs(t, <-closed, ErrClosed)
+		assert.Empty(t, chanBroadcastCollect(sink))
+		assert.Equal(t, uint64(2), b.Dropped())
+	})
 
+	t.Run("policy drop oldest broadcast cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		source := make(chan int)
 		b := NewChanBroadcast(ctx, source, 0)
+
+		var reason error
+		sink := make(chan int)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			Policy:  ChanBroadcastDropOldest,
+			OnClose: func(err error) { reason = err },
+		}))
+
+		// Nobody is receiving, the sink must be closed anyway.
+		source <- 1
+		source <- 2
+		cancel()
+		b.Wait()
+
+		values := chanBroadcastCollect(sink)
+		assert.ErrorIs(t, reason, context.Canceled)
+		assert.Equal(t, uint64(2-len(values)), b.Dropped())
+	})
+
+	t.Run("policy block", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
 		sink := make(chan int)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastBlock,
+		}))
+
+		go func() {
+			for i := 1; i <= 3; i++ {
+				source <- i
+			}
+			close(source)
+		}()
+		assert.Equal(t, []int{1, 2, 3}, chanBroadcastCollect(sink))
+		assert.Equal(t, uint64(0), b.Dropped())
+	})
+
+	t.Run("policy block timeout", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		clock := NewClockFake(time.Unix(0, 0))
+		var dropped []int
+		sink := make(chan int)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			Policy:  ChanBroadcastBlock,
+			Timeout: time.Second,
+			Clock:   clock,
+			OnDrop:  func(value int) { dropped = append(dropped, value) },
+		}))
+
+		source <- 1
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+
+		go func() { source <- 2 }()
+		assert.Equal(t, 2, <-sink)
+		assert.Equal(t, []int{1}, dropped)
+		assert.Equal(t, uint64(1), b.Dropped())
+	})
+
+	t.Run("policy block cancel", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		ctx, cancel := context.WithCancel(context.TODO())
+		sink, sending := make(chan int), make(chan struct{})
+		assert.NoError(t, b.AddOptions(ctx, sink, ChanBroadcastSubOptions[int]{
+			Policy:  ChanBroadcastBlock,
+			OnClose: func(error) { wg.Done() },
+			Filter: func(int) bool {
+				close(sending)
+				return true
+			},
+		}))
+
+		// Canceling once the broadcaster is about to block.
+		source <- 1
+		<-sending
+		cancel()
+		wg.Wait()
+
+		_, more := <-sink
+		assert.False(t, more)
+		assert.Equal(t, uint64(1), b.Dropped())
+	})
+
+	t.Run("policy block delete", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		stuck, other := make(chan int), make(chan int, 1)
+		for _, sink := range []chan int{stuck, other} {
+			assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+				Policy: ChanBroadcastBlock,
+			}))
+		}
+
+		// Blocks on the stuck sink, as nobody is receiving.
+		source <- 1
+		b.Delete(other)
+		b.Delete(stuck)
+
+		_, more := <-stuck
+		assert.False(t, more)
+		// Either got the value before being deleted, or not.
+		assert.LessOrEqual(t, len(chanBroadcastCollect(other)), 1)
+		// The value the broadcaster was stuck on.
+		assert.Equal(t, uint64(1), b.Dropped())
+	})
+
+	t.Run("filter/map", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		var dropped []int
+		evens, odds := make(chan int, 10), make(chan int, 1)
+		assert.NoError(t, b.AddOptions(nil, evens, ChanBroadcastSubOptions[int]{
+			Filter: func(value int) bool { return value%2 == 0 },
+		}))
+		assert.NoError(t, b.AddOptions(nil, odds, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastDropNewest,
+			Filter: func(value int) bool { return value%2 == 1 },
+			Map:    func(value int) int { return -value },
+			OnDrop: func(value int) { dropped = append(dropped, value) },
+		}))
+
+		for i := 1; i <= 6; i++ {
+			source <- i
+		}
+		close(source)
+		b.Wait()
+
+		assert.Equal(t, []int{2, 4, 6}, chanBroadcastCollect(evens))
+		assert.Equal(t, []int{-1}, chanBroadcastCollect(odds))
+		// The filtered out values don't count as dropped.
+		assert.Equal(t, []int{-3, -5}, dropped)
+		assert.Equal(t, uint64(2), b.Dropped())
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcastReplay(nil, source, 0, 3)
+
+		early := make(chan int, 10)
+		b.Add(early)
+		for i := 1; i <= 5; i++ {
+			source <- i
+		}
+
+		late := make(chan int, 10)
+		b.Add(late)
+		odds := make(chan int, 10)
+		assert.NoError(t, b.AddOptions(nil, odds, ChanBroadcastSubOptions[int]{
+			Filter: func(value int) bool { return value%2 == 1 },
+		}))
+
+		// Replacing doesn't replay.
+		b.Add(early)
+
+		source <- 6
+		close(source)
+		b.Wait()
+
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, chanBroadcastCollect(early))
+		assert.Equal(t, []int{3, 4, 5, 6}, chanBroadcastCollect(late))
+		assert.Equal(t, []int{3, 5}, chanBroadcastCollect(odds))
+	})
+
+	t.Run("replay latest overflow", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcastReplay(nil, source, 0, 1)
+		source <- 1
+		source <- 2
+
+		// No room for the replay, disconnected right away.
+		var reason error
+		var wg sync.WaitGroup
+		wg.Add(1)
+		sink := make(chan int)
+		assert.NoError(t, b.AddOptions(nil, sink, ChanBroadcastSubOptions[int]{
+			OnClose: func(err error) {
+				reason = err
+				wg.Done()
+			},
+		}))
+		wg.Wait()
+		_, more := <-sink
+		assert.False(t, more)
+		assert.ErrorIs(t, reason, ErrFull)
+
+		sink = make(chan int, 1)
 		b.Add(sink)
+		assert.Equal(t, 2, <-sink)
+	})
 
+	t.Run("subscribe", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		sink, unsubscribe := b.Subscribe(nil, 1)
 		source <- 1
 		assert.Equal(t, 1, <-sink)
+
+		unsubscribe()
+		// Must not panic.
+		unsubscribe()
+		_, more := <-sink
+		assert.False(t, more)
+	})
+
+	t.Run("subscribe options", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		sink, unsubscribe, err := b.SubscribeOptions(nil, 0, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastDropOldest,
+		})
+		assert.NoError(t, err)
+		defer unsubscribe()
+
+		source <- 1
+		assert.Equal(t, 1, <-sink)
+
+		_, _, err = b.SubscribeOptions(nil, 0, ChanBroadcastSubOptions[int]{Policy: -1})
+		assert.ErrorIs(t, err, ErrBadOptions)
+	})
+
+	t.Run("subscribe canceled", func(t *testing.T) {
+		b := NewChanBroadcast(nil, make(chan int), 0)
+
+		ctx, cancel := context.WithCancel(context.TODO())
 		cancel()
 
-		// Giving the broadcast time to handle cancellation.
-		time.Sleep(10 * time.Millisecond)
+		var reason error
+		sink, unsubscribe, err := b.SubscribeOptions(ctx, 0, ChanBroadcastSubOptions[int]{
+			OnClose: func(err error) { reason = err },
+		})
+		assert.NoError(t, err)
+		_, more := <-sink
+		assert.False(t, more)
+		assert.ErrorIs(t, reason, context.Canceled)
+		// Must not panic.
+		unsubscribe()
+	})
+
+	t.Run("subscribe broadcast done", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+		close(source)
+		b.Wait()
 
+		var reason error
+		sink, unsubscribe, err := b.SubscribeOptions(nil, 0, ChanBroadcastSubOptions[int]{
+			OnClose: func(err error) { reason = err },
+		})
+		assert.NoError(t, err)
+		_, more := <-sink
+		assert.False(t, more)
+		assert.ErrorIs(t, reason, ErrClosed)
+		unsubscribe()
+	})
+
+	t.Run("subscribe unread", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		sink, unsubscribe, err := b.SubscribeOptions(nil, 0, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastDropOldest,
+			Buffer: 2,
+		})
+		assert.NoError(t, err)
+		source <- 1
+		source <- 2
+
+		// Nobody is receiving, must return anyway.
+		unsubscribe()
+		assert.Empty(t, chanBroadcastCollect(sink))
+		assert.Equal(t, uint64(2), b.Dropped())
+	})
+
+	t.Run("subscribe unread source closed", func(t *testing.T) {
+		source := make(chan int)
+		b := NewChanBroadcast(nil, source, 0)
+
+		sink, unsubscribe, err := b.SubscribeOptions(nil, 0, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastDropOldest,
+			Buffer: 2,
+		})
+		assert.NoError(t, err)
+		source <- 1
+		source <- 2
+		close(source)
+		b.Wait()
+
+		// The buffered values are being flushed, but nobody is receiving.
+		unsubscribe()
+		assert.Empty(t, chanBroadcastCollect(sink))
+		assert.Equal(t, uint64(2), b.Dropped())
+	})
+
+	t.Run("subscribe broadcast canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		b := NewChanBroadcast(ctx, make(chan int), 0)
+		cancel()
+		b.Wait()
+
+		var reason error
+		sink, unsubscribe, err := b.SubscribeOptions(nil, 0, ChanBroadcastSubOptions[int]{
+			OnClose: func(err error) { reason = err },
+		})
+		assert.NoError(t, err)
+		_, more := <-sink
+		assert.False(t, more)
+		assert.ErrorIs(t, reason, context.Canceled)
+		unsubscribe()
+	})
+
+	t.Run("broadcast cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		source := make(chan int)
+
+		b := NewChanBroadcast(ctx, source, 0)
+		sink := make(chan int)
+		b.Add(sink)
+
+		source <- 1
+		assert.Equal(t, 1, <-sink)
+		cancel()
+
+		// Blocks until the broadcast handles cancellation.
 		_, more := <-sink
 		assert.False(t, more)
 	})
@@ -139,3 +604,12 @@ func TestChanBroadcast(t *testing.T) {
 		assert.Equal(t, context.Canceled, b.WaitContext(ctx))
 	})
 }
+
+// chanBroadcastCollect reads the sink until it's closed.
+func chanBroadcastCollect[T any](sink <-chan T) []T {
+	var values []T
+	for value := range sink {
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,151 @@
+package sly
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Clock is a time source of the time-dependent primitives.
+	// See ClockReal and ClockFake.
+	Clock interface {
+		// Now returns the current time.
+		Now() time.Time
+		// NewTimer creates a new timer firing after at least d.
+		NewTimer(d time.Duration) ClockTimer
+	}
+
+	// ClockTimer is a timer created by Clock, see time.Timer.
+	ClockTimer interface {
+		// C returns the channel the time is delivered on.
+		C() <-chan time.Time
+		// Stop prevents the timer from firing.
+		// Returns false if the timer has already fired or been stopped.
+		Stop() bool
+	}
+
+	// ClockReal is a Clock backed by the time package.
+	ClockReal struct{}
+
+	// ClockFake is a manually advanced Clock for testing.
+	ClockFake struct {
+		mu     sync.Mutex
+		now    time.Time
+		timers map[*clockFakeTimer]struct{}
+		// Broadcasts the timers change.
+		changed *sync.Cond
+	}
+
+	clockRealTimer struct {
+		timer *time.Timer
+	}
+
+	clockFakeTimer struct {
+		clock *ClockFake
+		at    time.Time
+		c     chan time.Time
+	}
+)
+
+// Now returns time.Now().
+func (ClockReal) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer returns time.NewTimer(d).
+func (ClockReal) NewTimer(d time.Duration) ClockTimer {
+	return clockRealTimer{timer: time.NewTimer(d)}
+}
+
+func (t clockRealTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t clockRealTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// NewClockFake creates a new fake clock.
+//
+//	now: Initial time.
+func NewClockFake(now time.Time) *ClockFake {
+	c := ClockFake{
+		now:    now,
+		timers: make(map[*clockFakeTimer]struct{}),
+	}
+	c.changed = sync.NewCond(&c.mu)
+	return &c
+}
+
+// Now returns the fake time.
+func (c *ClockFake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a new timer firing once the clock is advanced by d.
+// Fires immediately if d isn't positive.
+func (c *ClockFake) NewTimer(d time.Duration) ClockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := clockFakeTimer{
+		clock: c,
+		at:    c.now.Add(d),
+		c:     make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		t.c <- c.now
+		return &t
+	}
+
+	c.timers[&t] = struct{}{}
+	c.changed.Broadcast()
+	return &t
+}
+
+// Advance moves the clock forward, firing the due timers.
+//
+//	d: Duration to advance by.
+func (c *ClockFake) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for t := range c.timers {
+		if t.at.After(c.now) {
+			continue
+		}
+
+		t.c <- c.now
+		delete(c.timers, t)
+	}
+	c.changed.Broadcast()
+}
+
+// BlockUntil blocks until there are at least n pending timers.
+//
+// Lets the test wait for the code under test to start waiting.
+func (c *ClockFake) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+func (t *clockFakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *clockFakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	_, pending := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	t.clock.changed.Broadcast()
+	return pending
+}
@@ -0,0 +1,66 @@
+package sly
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClockReal(t *testing.T) {
+	clock := ClockReal{}
+	assert.WithinDuration(t, time.Now(), clock.Now(), time.Second)
+
+	timer := clock.NewTimer(time.Millisecond)
+	<-timer.C()
+	assert.False(t, timer.Stop())
+}
+
+func TestClockFake(t *testing.T) {
+	t.Run("advance", func(t *testing.T) {
+		start := time.Unix(0, 0)
+		clock := NewClockFake(start)
+
+		timer := clock.NewTimer(2 * time.Second)
+		clock.Advance(time.Second)
+		assert.Equal(t, start.Add(time.Second), clock.Now())
+
+		select {
+		case <-timer.C():
+			t.Fatal("timer fired early")
+		default:
+		}
+
+		clock.Advance(time.Second)
+		assert.Equal(t, start.Add(2*time.Second), <-timer.C())
+		assert.False(t, timer.Stop())
+	})
+
+	t.Run("fire immediately", func(t *testing.T) {
+		clock := NewClockFake(time.Unix(0, 0))
+		timer := clock.NewTimer(0)
+		assert.Equal(t, time.Unix(0, 0), <-timer.C())
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		clock := NewClockFake(time.Unix(0, 0))
+		timer := clock.NewTimer(time.Second)
+		assert.True(t, timer.Stop())
+
+		clock.Advance(time.Second)
+		select {
+		case <-timer.C():
+			t.Fatal("stopped timer fired")
+		default:
+		}
+	})
+
+	t.Run("block until", func(t *testing.T) {
+		clock := NewClockFake(time.Unix(0, 0))
+
+		go func() {
+			clock.NewTimer(time.Second)
+			clock.NewTimer(time.Second)
+		}()
+		clock.BlockUntil(2)
+	})
+}
@@ -1,6 +1,9 @@
 package sly
 
-import "golang.org/x/exp/constraints"
+import (
+	"golang.org/x/exp/constraints"
+	"strings"
+)
 
 // Compare is a comparator func. See bounded methods for more.
 type Compare[T any] func(T, T) int
@@ -52,3 +55,141 @@ func CompareReverse[T any](compare Compare[T]) Compare[T] {
 		return compare(a, b) * -1
 	}
 }
+
+// Then chains the comparators, the other one breaks the ties.
+func (c Compare[T]) Then(other Compare[T]) Compare[T] {
+	return func(a, b T) int {
+		if cmp := c(a, b); cmp != 0 {
+			return cmp
+		}
+		return other(a, b)
+	}
+}
+
+// CompareBy returns the comparator of the keys projected by the key func.
+func CompareBy[T, K any](key func(T) K, compare Compare[K]) Compare[T] {
+	return func(a, b T) int {
+		return compare(key(a), key(b))
+	}
+}
+
+// CompareSlices returns the lexicographic comparator of slices.
+// The prefix goes first.
+func CompareSlices[T any](compare Compare[T]) Compare[[]T] {
+	return func(a, b []T) int {
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if cmp := compare(a[i], b[i]); cmp != 0 {
+				return cmp
+			}
+		}
+		return CompareOrdered(len(a), len(b))
+	}
+}
+
+// CompareMaps returns the lexicographic comparator of maps,
+// viewed as the slices of entries sorted by key.
+func CompareMaps[K comparable, V any](keyCompare Compare[K], valueCompare Compare[V]) Compare[map[K]V] {
+	return func(a, b map[K]V) int {
+		ak, bk := compareMapKeys(a, keyCompare), compareMapKeys(b, keyCompare)
+		for i := 0; i < len(ak) && i < len(bk); i++ {
+			if cmp := keyCompare(ak[i], bk[i]); cmp != 0 {
+				return cmp
+			}
+			if cmp := valueCompare(a[ak[i]], b[bk[i]]); cmp != 0 {
+				return cmp
+			}
+		}
+		return CompareOrdered(len(ak), len(bk))
+	}
+}
+
+// CompareNilFirst returns the pointer comparator, which places nil
+// before the others and compares the rest by the pointed values.
+func CompareNilFirst[T any](compare Compare[T]) Compare[*T] {
+	return func(a, b *T) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		case b == nil:
+			return 1
+		}
+		return compare(*a, *b)
+	}
+}
+
+// CompareNilLast returns the pointer comparator, which places nil
+// after the others and compares the rest by the pointed values.
+func CompareNilLast[T any](compare Compare[T]) Compare[*T] {
+	return func(a, b *T) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		case b == nil:
+			return -1
+		}
+		return compare(*a, *b)
+	}
+}
+
+// CompareNatural is a natural order comparator for strings, which
+// compares the runs of digits by their numeric values: "a2" < "a10".
+//
+// Numerically equal runs with fewer leading zeros go first,
+// unless the rest of the strings differ.
+func CompareNatural(a, b string) int {
+	zeros := 0
+	for len(a) > 0 && len(b) > 0 {
+		if !compareIsDigit(a[0]) || !compareIsDigit(b[0]) {
+			if a[0] != b[0] {
+				return CompareOrdered(a[0], b[0])
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		na, nb := compareDigits(a), compareDigits(b)
+		// Leading zeros don't count.
+		ta, tb := strings.TrimLeft(a[:na], "0"), strings.TrimLeft(b[:nb], "0")
+		if len(ta) != len(tb) {
+			return CompareOrdered(len(ta), len(tb))
+		}
+		if ta != tb {
+			return CompareOrdered(ta, tb)
+		}
+		if zeros == 0 {
+			zeros = CompareOrdered(na, nb)
+		}
+		a, b = a[na:], b[nb:]
+	}
+
+	if len(a) != len(b) {
+		return CompareOrdered(len(a), len(b))
+	}
+	return zeros
+}
+
+func compareMapKeys[K comparable, V any](m map[K]V, compare Compare[K]) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	SortPdq(keys, compare)
+	return keys
+}
+
+func compareIsDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// compareDigits returns the length of the leading run of digits.
+func compareDigits(s string) int {
+	n := 0
+	for n < len(s) && compareIsDigit(s[n]) {
+		n++
+	}
+	return n
+}
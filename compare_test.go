@@ -35,3 +35,72 @@ func TestCompareMethods(t *testing.T) {
 	assert.True(t, compare.GreaterOrEqual(2, 2))
 	assert.False(t, compare.GreaterOrEqual(2, 3))
 }
+
+func TestCompareThen(t *testing.T) {
+	type person struct {
+		name string
+		age  int
+	}
+
+	compare := CompareBy(func(p person) string { return p.name }, CompareOrdered[string]).
+		Then(CompareBy(func(p person) int { return p.age }, CompareOrdered[int]))
+
+	assert.Equal(t, -1, compare(person{"a", 2}, person{"b", 1}))
+	assert.Equal(t, 1, compare(person{"a", 2}, person{"a", 1}))
+	assert.Equal(t, 0, compare(person{"a", 1}, person{"a", 1}))
+}
+
+func TestCompareSlices(t *testing.T) {
+	compare := CompareSlices(CompareOrdered[int])
+	assert.Equal(t, -1, compare([]int{1, 2}, []int{1, 3}))
+	assert.Equal(t, -1, compare([]int{1, 2}, []int{1, 2, 0}))
+	assert.Equal(t, 1, compare([]int{2}, []int{1, 2, 0}))
+	assert.Equal(t, 0, compare(nil, []int{}))
+}
+
+func TestCompareMaps(t *testing.T) {
+	compare := CompareMaps(CompareOrdered[string], CompareOrdered[int])
+	assert.Equal(t, -1, compare(map[string]int{"a": 1}, map[string]int{"a": 2}))
+	assert.Equal(t, -1, compare(map[string]int{"a": 1, "b": 2}, map[string]int{"a": 1, "c": 0}))
+	assert.Equal(t, 1, compare(map[string]int{"a": 1, "b": 2}, map[string]int{"a": 1}))
+	assert.Equal(t, 0, compare(map[string]int{"a": 1, "b": 2}, map[string]int{"b": 2, "a": 1}))
+}
+
+func TestCompareNil(t *testing.T) {
+	one, two := 1, 2
+
+	first := CompareNilFirst(CompareOrdered[int])
+	assert.Equal(t, -1, first(nil, &one))
+	assert.Equal(t, 1, first(&one, nil))
+	assert.Equal(t, 0, first(nil, nil))
+	assert.Equal(t, -1, first(&one, &two))
+
+	last := CompareNilLast(CompareOrdered[int])
+	assert.Equal(t, 1, last(nil, &one))
+	assert.Equal(t, -1, last(&one, nil))
+	assert.Equal(t, 0, last(nil, nil))
+	assert.Equal(t, 1, last(&two, &one))
+}
+
+func TestCompareNatural(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"a2", "a10", -1},
+		{"a10", "a2", 1},
+		{"a10", "a10", 0},
+		{"file9.txt", "file10.txt", -1},
+		{"x1y2", "x1y10", -1},
+		{"a01", "a1", 1},
+		{"a01b", "a1c", -1},
+		{"a", "a1", -1},
+		{"b", "a1", 1},
+		{"1", "a", -1},
+		{"", "", 0},
+		{"007", "7", 1},
+		{"12345678901234567890", "12345678901234567891", -1},
+	} {
+		assert.Equal(t, tt.want, CompareNatural(tt.a, tt.b), "%q vs %q", tt.a, tt.b)
+	}
+}
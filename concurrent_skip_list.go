@@ -0,0 +1,311 @@
+package sly
+
+import (
+	"math/bits"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Max height of the ConcurrentSkipList towers. With the 1/4 level
+// probability, it's enough for any practical number of elements.
+const concurrentSkipListMaxLevel = 32
+
+type (
+	// ConcurrentSkipList is a thread-safe ordered set based on the lazy
+	// skip list. Reads and range scans are lock-free, writes only lock the
+	// nodes adjacent to the one being added or removed.
+	//
+	// Iteration is weakly consistent: it never sees the same element twice
+	// and never misses the elements that were present for its whole
+	// duration, but it may or may not see the concurrent changes.
+	ConcurrentSkipList[T any] struct {
+		// Atomic. Must stay the first field, as only that one is
+		// guaranteed to be 64-bit aligned on the 32-bit platforms.
+		len int64
+
+		head    *concurrentSkipListNode[T]
+		compare Compare[T]
+	}
+
+	concurrentSkipListNode[T any] struct {
+		value T
+		// Atomic *concurrentSkipListNode[T] per level, nil past the end.
+		next []unsafe.Pointer
+		lock SpinLock
+		// Set once the node is logically removed.
+		marked int32
+		// Set once the node is linked at all of its levels.
+		fullyLinked int32
+	}
+)
+
+// NewConcurrentSkipList creates a new concurrent skip list.
+//
+//	compare: Comparator function.
+func NewConcurrentSkipList[T any](compare Compare[T]) *ConcurrentSkipList[T] {
+	return &ConcurrentSkipList[T]{
+		head: &concurrentSkipListNode[T]{
+			next:        make([]unsafe.Pointer, concurrentSkipListMaxLevel),
+			fullyLinked: 1,
+		},
+		compare: compare,
+	}
+}
+
+// Len returns the number of elements in the list.
+func (l *ConcurrentSkipList[T]) Len() int {
+	return int(atomic.LoadInt64(&l.len))
+}
+
+// Add adds the value to the list.
+//
+//	x: Value to add.
+//
+// Returns false if there's an equal value in the list already.
+func (l *ConcurrentSkipList[T]) Add(x T) bool {
+	var preds, succs [concurrentSkipListMaxLevel]*concurrentSkipListNode[T]
+	level := concurrentSkipListRandomLevel()
+
+	for {
+		if found := l.find(x, &preds, &succs); found != -1 {
+			node := succs[found]
+			if node.isMarked() {
+				// Being removed, retrying once it's unlinked.
+				runtime.Gosched()
+				continue
+			}
+
+			// Being added, waiting for it to become visible.
+			for !node.isFullyLinked() {
+				runtime.Gosched()
+			}
+			return false
+		}
+
+		locked, valid := -1, true
+		for i := 0; valid && i < level; i++ {
+			pred, succ := preds[i], succs[i]
+			if i == 0 || pred != preds[i-1] {
+				pred.lock.Lock()
+				locked = i
+			}
+			valid = !pred.isMarked() && (succ == nil || !succ.isMarked()) && pred.load(i) == succ
+		}
+		if !valid {
+			concurrentSkipListUnlock(&preds, locked)
+			continue
+		}
+
+		node := &concurrentSkipListNode[T]{
+			value: x,
+			next:  make([]unsafe.Pointer, level),
+		}
+		for i := 0; i < level; i++ {
+			node.next[i] = unsafe.Pointer(succs[i])
+		}
+		for i := 0; i < level; i++ {
+			preds[i].store(i, node)
+		}
+		atomic.StoreInt32(&node.fullyLinked, 1)
+
+		concurrentSkipListUnlock(&preds, locked)
+		atomic.AddInt64(&l.len, 1)
+		return true
+	}
+}
+
+// Remove removes the value from the list.
+//
+//	x: Value to remove.
+//
+// Returns false if there was no such value.
+func (l *ConcurrentSkipList[T]) Remove(x T) bool {
+	var preds, succs [concurrentSkipListMaxLevel]*concurrentSkipListNode[T]
+	var victim *concurrentSkipListNode[T]
+
+	for {
+		found := l.find(x, &preds, &succs)
+		if victim == nil {
+			if found == -1 {
+				return false
+			}
+
+			// Only the fully linked nodes found at their top level
+			// are safe to remove, others are still being added.
+			node := succs[found]
+			if !node.isFullyLinked() || len(node.next)-1 != found || node.isMarked() {
+				return false
+			}
+
+			node.lock.Lock()
+			if node.isMarked() {
+				node.lock.Unlock()
+				return false
+			}
+			atomic.StoreInt32(&node.marked, 1)
+			victim = node
+		}
+
+		locked, valid := -1, true
+		for i := 0; valid && i < len(victim.next); i++ {
+			pred := preds[i]
+			if i == 0 || pred != preds[i-1] {
+				pred.lock.Lock()
+				locked = i
+			}
+			valid = !pred.isMarked() && pred.load(i) == victim
+		}
+		if !valid {
+			concurrentSkipListUnlock(&preds, locked)
+			continue
+		}
+
+		for i := len(victim.next) - 1; i >= 0; i-- {
+			preds[i].store(i, victim.load(i))
+		}
+
+		victim.lock.Unlock()
+		concurrentSkipListUnlock(&preds, locked)
+		atomic.AddInt64(&l.len, -1)
+		return true
+	}
+}
+
+// Contains reports whether there's an equal value in the list.
+//
+//	x: Value to look up.
+func (l *ConcurrentSkipList[T]) Contains(x T) bool {
+	_, ok := l.Get(x)
+	return ok
+}
+
+// Get looks up the value. Useful when the comparator only
+// looks at a part of T, e.g. a key.
+//
+//	x: Value to look up.
+//
+// Returns the value stored in the list, or the default value
+// and false if there's no such value.
+func (l *ConcurrentSkipList[T]) Get(x T) (T, bool) {
+	var preds, succs [concurrentSkipListMaxLevel]*concurrentSkipListNode[T]
+	if found := l.find(x, &preds, &succs); found != -1 {
+		node := succs[found]
+		if node.isFullyLinked() && !node.isMarked() {
+			return node.value, true
+		}
+	}
+
+	var z T
+	return z, false
+}
+
+// Min returns the smallest value in the list.
+//
+// Returns the default value and false if the list is empty.
+func (l *ConcurrentSkipList[T]) Min() (T, bool) {
+	var min T
+	var ok bool
+	l.Ascend(func(x T) bool {
+		min, ok = x, true
+		return false
+	})
+	return min, ok
+}
+
+// Ascend iterates over the list in ascending order.
+//
+//	fn: Iteration callback, returns false to stop.
+func (l *ConcurrentSkipList[T]) Ascend(fn func(x T) bool) {
+	l.ascend(l.head.load(0), nil, fn)
+}
+
+// AscendRange iterates over the list in ascending order
+// within the [from, to) range.
+//
+//	from: Range start, inclusive.
+//	to: Range end, exclusive.
+//	fn: Iteration callback, returns false to stop.
+func (l *ConcurrentSkipList[T]) AscendRange(from, to T, fn func(x T) bool) {
+	pred := l.head
+	for i := concurrentSkipListMaxLevel - 1; i >= 0; i-- {
+		for curr := pred.load(i); curr != nil && l.compare.Less(curr.value, from); curr = pred.load(i) {
+			pred = curr
+		}
+	}
+	l.ascend(pred.load(0), &to, fn)
+}
+
+func (l *ConcurrentSkipList[T]) ascend(node *concurrentSkipListNode[T], to *T, fn func(x T) bool) {
+	for ; node != nil; node = node.load(0) {
+		if to != nil && l.compare.GreaterOrEqual(node.value, *to) {
+			return
+		}
+		if !node.isFullyLinked() || node.isMarked() {
+			continue
+		}
+		if !fn(node.value) {
+			return
+		}
+	}
+}
+
+// find looks up the predecessors and successors of x on every level.
+//
+// Returns the highest level x was found at, or -1 if it wasn't.
+func (l *ConcurrentSkipList[T]) find(
+	x T,
+	preds, succs *[concurrentSkipListMaxLevel]*concurrentSkipListNode[T],
+) int {
+	found := -1
+	pred := l.head
+	for i := concurrentSkipListMaxLevel - 1; i >= 0; i-- {
+		curr := pred.load(i)
+		for curr != nil && l.compare.Less(curr.value, x) {
+			pred = curr
+			curr = pred.load(i)
+		}
+		if found == -1 && curr != nil && l.compare.Equal(curr.value, x) {
+			found = i
+		}
+		preds[i], succs[i] = pred, curr
+	}
+	return found
+}
+
+func (n *concurrentSkipListNode[T]) load(level int) *concurrentSkipListNode[T] {
+	return (*concurrentSkipListNode[T])(atomic.LoadPointer(&n.next[level]))
+}
+
+func (n *concurrentSkipListNode[T]) store(level int, next *concurrentSkipListNode[T]) {
+	atomic.StorePointer(&n.next[level], unsafe.Pointer(next))
+}
+
+func (n *concurrentSkipListNode[T]) isMarked() bool {
+	return atomic.LoadInt32(&n.marked) == 1
+}
+
+func (n *concurrentSkipListNode[T]) isFullyLinked() bool {
+	return atomic.LoadInt32(&n.fullyLinked) == 1
+}
+
+// concurrentSkipListUnlock unlocks the distinct predecessors
+// up to the given level inclusive.
+func concurrentSkipListUnlock[T any](preds *[concurrentSkipListMaxLevel]*concurrentSkipListNode[T], level int) {
+	for i := 0; i <= level; i++ {
+		if i == 0 || preds[i] != preds[i-1] {
+			preds[i].lock.Unlock()
+		}
+	}
+}
+
+// concurrentSkipListRandomLevel picks the new tower height,
+// each level being 4 times less likely than the previous one.
+func concurrentSkipListRandomLevel() int {
+	level := bits.TrailingZeros64(rand.Uint64())/2 + 1
+	if level > concurrentSkipListMaxLevel {
+		return concurrentSkipListMaxLevel
+	}
+	return level
+}
@@ -0,0 +1,174 @@
+package sly
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSkipList(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		l := NewConcurrentSkipList(CompareOrdered[int])
+		assert.Equal(t, 0, l.Len())
+		assert.False(t, l.Contains(1))
+		assert.False(t, l.Remove(1))
+
+		_, ok := l.Min()
+		assert.False(t, ok)
+		l.Ascend(func(int) bool {
+			t.Fatal("must not be called")
+			return true
+		})
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		l := NewConcurrentSkipList(CompareOrdered[int])
+		assert.True(t, l.Add(2))
+		assert.True(t, l.Add(1))
+		assert.False(t, l.Add(2))
+		assert.Equal(t, 2, l.Len())
+		assert.True(t, l.Contains(2))
+
+		min, ok := l.Min()
+		assert.Equal(t, 1, min)
+		assert.True(t, ok)
+
+		assert.True(t, l.Remove(1))
+		assert.False(t, l.Remove(1))
+		assert.False(t, l.Contains(1))
+		assert.Equal(t, 1, l.Len())
+	})
+
+	t.Run("get", func(t *testing.T) {
+		type entry struct {
+			key   int
+			value string
+		}
+		l := NewConcurrentSkipList(CompareBy(func(e entry) int { return e.key }, CompareOrdered[int]))
+		l.Add(entry{1, "a"})
+
+		e, ok := l.Get(entry{key: 1})
+		assert.Equal(t, entry{1, "a"}, e)
+		assert.True(t, ok)
+		_, ok = l.Get(entry{key: 2})
+		assert.False(t, ok)
+	})
+
+	t.Run("iterate", func(t *testing.T) {
+		l := NewConcurrentSkipList(CompareOrdered[int])
+		for i := 999; i >= 0; i-- {
+			l.Add(i)
+		}
+
+		var values []int
+		l.AscendRange(100, 105, func(x int) bool {
+			values = append(values, x)
+			return true
+		})
+		assert.Equal(t, []int{100, 101, 102, 103, 104}, values)
+
+		values = nil
+		l.Ascend(func(x int) bool {
+			values = append(values, x)
+			return len(values) < 3
+		})
+		assert.Equal(t, []int{0, 1, 2}, values)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		const (
+			writers = 8
+			n       = 1000
+		)
+		l := NewConcurrentSkipList(CompareOrdered[int])
+		// Stable elements must be seen by every scan.
+		for i := 0; i < n; i++ {
+			l.Add(i * writers * 2)
+		}
+
+		var wg sync.WaitGroup
+		done := make(chan struct{})
+		for w := 0; w < writers; w++ {
+			w := w
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < n; i++ {
+					x := i*writers*2 + w + 1
+					assert.True(t, l.Add(x))
+					if i&1 == 1 {
+						assert.True(t, l.Remove(x))
+					}
+				}
+			}()
+		}
+
+		var scanners sync.WaitGroup
+		for s := 0; s < 4; s++ {
+			scanners.Add(1)
+			go func() {
+				defer scanners.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+
+					stable, prev := 0, -1
+					l.Ascend(func(x int) bool {
+						assert.Greater(t, x, prev)
+						if x%(writers*2) == 0 {
+							stable++
+						}
+						prev = x
+						return true
+					})
+					assert.Equal(t, n, stable)
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(done)
+		scanners.Wait()
+
+		assert.Equal(t, n+writers*n/2, l.Len())
+	})
+}
+
+func FuzzConcurrentSkipList(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49, 140, 168, 12})
+	f.Add([]byte(nil))
+	f.Fuzz(func(t *testing.T, bs []byte) {
+		l := NewConcurrentSkipList(CompareOrdered[int])
+		want := make(map[int]struct{})
+		for _, b := range bs {
+			x := int(b & 0x7f)
+			_, ok := want[x]
+			if b&0x80 != 0 {
+				assert.Equal(t, ok, l.Remove(x))
+				delete(want, x)
+			} else {
+				assert.Equal(t, !ok, l.Add(x))
+				want[x] = struct{}{}
+			}
+		}
+
+		values := make([]int, 0, len(want))
+		for x := range want {
+			assert.True(t, l.Contains(x))
+			values = append(values, x)
+		}
+		sort.Ints(values)
+		assert.Equal(t, len(values), l.Len())
+
+		ascended := make([]int, 0, len(values))
+		l.Ascend(func(x int) bool {
+			ascended = append(ascended, x)
+			return true
+		})
+		assert.Equal(t, values, ascended)
+	})
+}
@@ -0,0 +1,163 @@
+package sly
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// DelayQueueOptions are used to construct a new delay queue.
+	//
+	//  Locker: Queue lock. If nil, then SpinLock.
+	//  Clock: Time source. If nil, then ClockReal.
+	DelayQueueOptions struct {
+		Locker sync.Locker
+		Clock  Clock
+	}
+
+	// The DelayQueue is a thread-safe queue, which elements can only
+	// be popped once they are due.
+	DelayQueue[T any] struct {
+		heap []delayQueueEntry[T]
+		// Wakes up one popper at a time, either to pop or to
+		// reschedule on the earliest element change.
+		wakeup chan struct{}
+		locker sync.Locker
+		clock  Clock
+	}
+
+	delayQueueEntry[T any] struct {
+		value T
+		at    time.Time
+	}
+)
+
+// NewDelayQueue creates a new delay queue.
+//
+//	opts: See DelayQueueOptions.
+//
+// Returns a pointer to the newly created delay queue.
+func NewDelayQueue[T any](opts DelayQueueOptions) *DelayQueue[T] {
+	if opts.Locker == nil {
+		opts.Locker = new(SpinLock)
+	}
+	if opts.Clock == nil {
+		opts.Clock = ClockReal{}
+	}
+
+	return &DelayQueue[T]{
+		wakeup: make(chan struct{}, 1),
+		locker: opts.Locker,
+		clock:  opts.Clock,
+	}
+}
+
+// Push an element onto the delay queue.
+//
+//	x: Element to push.
+//	at: Time the element becomes due.
+func (dq *DelayQueue[T]) Push(x T, at time.Time) {
+	dq.locker.Lock()
+	HeapPush(&dq.heap, delayQueueEntry[T]{value: x, at: at}, delayQueueCompare[T])
+	// The earliest element has changed, the poppers must reschedule.
+	if dq.heap[0].at.Equal(at) {
+		dq.notifyLF()
+	}
+	dq.locker.Unlock()
+}
+
+// PushAfter pushes an element onto the delay queue.
+//
+// This is a convenience function for Push with the due time
+// d away from now.
+//
+// See Push for more details.
+func (dq *DelayQueue[T]) PushAfter(x T, d time.Duration) {
+	dq.Push(x, dq.clock.Now().Add(d))
+}
+
+// Pop the earliest element from the delay queue.
+//
+//	ctx: Cancellation context. If nil, defaults to context.Background().
+//
+// Blocks indefinitely until the earliest element is due
+// or the context is done.
+//
+// Returns the default value and false in case the context is done.
+func (dq *DelayQueue[T]) Pop(ctx context.Context) (T, bool) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		var timer ClockTimer
+		dq.locker.Lock()
+		if len(dq.heap) != 0 {
+			wait := dq.heap[0].at.Sub(dq.clock.Now())
+			if wait <= 0 {
+				entry := HeapPop(&dq.heap, delayQueueCompare[T])
+				// Passing the signal on, the next one may be due already.
+				dq.notifyLF()
+				dq.locker.Unlock()
+				return entry.value, true
+			}
+
+			timer = dq.clock.NewTimer(wait)
+		}
+		dq.locker.Unlock()
+
+		if !dq.wait(ctx, timer) {
+			// Others may wait for the signal this popper has consumed.
+			dq.locker.Lock()
+			dq.notifyLF()
+			dq.locker.Unlock()
+
+			var z T
+			return z, false
+		}
+	}
+}
+
+// wait blocks until the wakeup signal, the timer fires or the context is done.
+//
+// Returns false in case the context is done.
+func (dq *DelayQueue[T]) wait(ctx context.Context, timer ClockTimer) bool {
+	var fire <-chan time.Time
+	if timer != nil {
+		defer timer.Stop()
+		fire = timer.C()
+	}
+
+	select {
+	case <-dq.wakeup:
+	case <-fire:
+	case <-ctx.Done():
+		return false
+	}
+	return true
+}
+
+// notifyLF wakes up a popper if the heap isn't empty.
+// Must be called with the lock held.
+func (dq *DelayQueue[T]) notifyLF() {
+	if len(dq.heap) == 0 {
+		return
+	}
+
+	select {
+	case dq.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// delayQueueCompare makes the earliest element the top one.
+func delayQueueCompare[T any](a, b delayQueueEntry[T]) int {
+	switch {
+	case a.at.Before(b.at):
+		return 1
+	case a.at.After(b.at):
+		return -1
+	}
+	return 0
+}
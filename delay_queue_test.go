@@ -0,0 +1,74 @@
+package sly
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDelayQueue(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		clock := NewClockFake(time.Unix(0, 0))
+		dq := NewDelayQueue[int](DelayQueueOptions{Clock: clock})
+
+		dq.PushAfter(3, 3*time.Second)
+		dq.PushAfter(1, time.Second)
+		dq.PushAfter(2, 2*time.Second)
+
+		popped := make(chan int)
+		go func() {
+			for i := 0; i < 3; i++ {
+				v, _ := dq.Pop(nil)
+				popped <- v
+			}
+		}()
+
+		for _, want := range []int{1, 2, 3} {
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			assert.Equal(t, want, <-popped)
+		}
+	})
+
+	t.Run("pop due", func(t *testing.T) {
+		dq := NewDelayQueue[int](DelayQueueOptions{})
+		dq.Push(1, time.Now().Add(-time.Hour))
+
+		v, ok := dq.Pop(nil)
+		assert.Equal(t, 1, v)
+		assert.True(t, ok)
+	})
+
+	t.Run("earlier push wakes popper", func(t *testing.T) {
+		clock := NewClockFake(time.Unix(0, 0))
+		dq := NewDelayQueue[int](DelayQueueOptions{Clock: clock})
+		dq.PushAfter(2, time.Hour)
+
+		popped := make(chan int)
+		go func() {
+			v, _ := dq.Pop(nil)
+			popped <- v
+		}()
+
+		clock.BlockUntil(1)
+		dq.PushAfter(1, time.Second)
+		clock.Advance(time.Second)
+		assert.Equal(t, 1, <-popped)
+	})
+
+	t.Run("pop unblock on cancel", func(t *testing.T) {
+		clock := NewClockFake(time.Unix(0, 0))
+		dq := NewDelayQueue[int](DelayQueueOptions{Clock: clock})
+		dq.PushAfter(1, time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			clock.BlockUntil(1)
+			cancel()
+		}()
+
+		_, ok := dq.Pop(ctx)
+		assert.False(t, ok)
+	})
+}
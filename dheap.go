@@ -0,0 +1,141 @@
+package sly
+
+import "fmt"
+
+// DHeapPush pushes the value onto the d-ary heap.
+//
+//	heapPtr: Heap pointer.
+//	x: Value to push.
+//	d: Heap arity, at least 2, panics otherwise.
+//	compare: Comparator function.
+//
+// Returns the heap with the value pushed onto it.
+func DHeapPush[T any](heapPtr *[]T, x T, d int, compare Compare[T]) []T {
+	dheapCheckArity(d)
+	heap := *heapPtr
+
+	heap = append(heap, x)
+	dheapSiftUp(heap, compare, SliceSwap[T], d, len(heap)-1)
+
+	*heapPtr = heap
+	return heap
+}
+
+// DHeapPop pops the max value from the d-ary heap.
+//
+//	heapPtr: Heap pointer.
+//	d: Heap arity, at least 2, panics otherwise.
+//	compare: Comparator function.
+//
+// Returns the max value.
+func DHeapPop[T any](heapPtr *[]T, d int, compare Compare[T]) T {
+	dheapCheckArity(d)
+	heap := *heapPtr
+
+	max := heap[0]
+	SliceSwap(heap, 0, len(heap)-1)
+	heap = heap[:len(heap)-1]
+	dheapSiftDown(heap, compare, SliceSwap[T], d, 0)
+
+	*heapPtr = heap
+	return max
+}
+
+// DHeapify makes its input a d-ary heap.
+//
+//	input: Slice to heapify.
+//	d: Heap arity, at least 2, panics otherwise.
+//	compare: Comparator function.
+func DHeapify[T any](input []T, d int, compare Compare[T]) {
+	dheapCheckArity(d)
+	dheapify(input, compare, SliceSwap[T], d)
+}
+
+// SortDHeap is an in-place d-ary heap sort.
+//
+//	input: Slice to sort.
+//	d: Heap arity, at least 2, panics otherwise.
+//	compare: Comparator function.
+func SortDHeap[T any](input []T, d int, compare Compare[T]) {
+	DHeapify(input, d, compare)
+	// Popping the max right past the shrinking heap end.
+	for n := len(input) - 1; n > 0; n-- {
+		SliceSwap(input, 0, n)
+		dheapSiftDown(input[:n], compare, SliceSwap[T], d, 0)
+	}
+}
+
+// dheapCheckArity panics on the arities the sifts can't handle:
+// 0 divides by zero, the negative ones never terminate.
+func dheapCheckArity(d int) {
+	if d < 2 {
+		panic(fmt.Errorf("%w: heap arity %d, must be at least 2", ErrBadOptions, d))
+	}
+}
+
+func dheapify[T any](input []T, compare Compare[T], swap heapSwap[T], d int) {
+	if d == 2 {
+		heapify(input, compare, swap)
+		return
+	}
+
+	// Any index beyond len(input)/d will be a leaf node.
+	for i := len(input)/d + 1; i >= 1; {
+		i--
+		dheapSiftDown(input, compare, swap, d, i)
+	}
+}
+
+func dheapSiftUp[T any](heap []T, compare Compare[T], swap heapSwap[T], d int, current int) {
+	if d == 2 {
+		heapSiftUp(heap, compare, swap, current)
+		return
+	}
+
+	for current != 0 {
+		parent := (current - 1) / d
+		// Stop if `current` is on its place.
+		if compare.LessOrEqual(heap[current], heap[parent]) {
+			return
+		}
+
+		swap(heap, current, parent)
+		current = parent
+	}
+}
+
+func dheapSiftDown[T any](heap []T, compare Compare[T], swap heapSwap[T], d int, current int) {
+	if d == 2 {
+		heapSiftDown(heap, compare, swap, current)
+		return
+	}
+
+	n := len(heap)
+	for {
+		firstChild := current*d + 1
+		// Stop if `current` is a leaf.
+		if firstChild >= n {
+			return
+		}
+
+		// Pick the child with the highest priority.
+		maxChild := firstChild
+		lastChild := firstChild + d
+		if lastChild > n {
+			lastChild = n
+		}
+		for i := firstChild + 1; i < lastChild; i++ {
+			if compare.Greater(heap[i], heap[maxChild]) {
+				maxChild = i
+			}
+		}
+
+		// Stop if `current` is on its place.
+		if compare.GreaterOrEqual(heap[current], heap[maxChild]) {
+			return
+		}
+
+		swap(heap, current, maxChild)
+		current = maxChild
+	}
+}
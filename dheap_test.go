@@ -0,0 +1,109 @@
+package sly
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestDHeapBadArity(t *testing.T) {
+	for _, d := range []int{1, 0, -1} {
+		msg := fmt.Sprintf("bad options: heap arity %d, must be at least 2", d)
+		heap := []int{1, 2, 3}
+		assert.PanicsWithError(t, msg, func() { DHeapPush(&heap, 4, d, CompareOrdered[int]) })
+		assert.PanicsWithError(t, msg, func() { DHeapPop(&heap, d, CompareOrdered[int]) })
+		assert.PanicsWithError(t, msg, func() { DHeapify(heap, d, CompareOrdered[int]) })
+		assert.PanicsWithError(t, msg, func() { SortDHeap(heap, d, CompareOrdered[int]) })
+	}
+}
+
+func FuzzDHeapPushPop(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49}, uint8(4))
+	f.Add([]byte(nil), uint8(3))
+	f.Fuzz(func(t *testing.T, bs []byte, d uint8) {
+		arity := int(d%8) + 2
+		h := make([]int, 0, len(bs))
+		for _, val := range bs {
+			DHeapPush(&h, int(val), arity, CompareOrdered[int])
+		}
+
+		hs := make([]int, 0, len(bs))
+		for len(h) > 0 {
+			hs = append(hs, DHeapPop(&h, arity, CompareOrdered[int]))
+		}
+		h = SliceReshape(h, len(bs))
+
+		sort.Slice(h, func(i, j int) bool {
+			return h[i] > h[j]
+		})
+
+		for i := range bs {
+			if h[i] != hs[i] {
+				t.Fatalf("want: %v at %v, have: %v", h[i], i, hs[i])
+			}
+		}
+	})
+}
+
+func FuzzSortDHeap(f *testing.F) {
+	f.Add([]byte{100, 48, 3, 82, 56, 62, 71, 39, 42, 22}, uint8(4))
+	f.Add([]byte(nil), uint8(3))
+	f.Fuzz(func(t *testing.T, bs []byte, d uint8) {
+		arity := int(d%8) + 2
+		h := make([]int, 0, len(bs))
+		for _, val := range bs {
+			h = append(h, int(val))
+		}
+
+		hs := append([]int(nil), h...)
+
+		sort.Slice(h, func(i, j int) bool {
+			return h[i] < h[j]
+		})
+
+		SortDHeap(hs, arity, CompareOrdered[int])
+
+		for i := range bs {
+			if h[i] != hs[i] {
+				t.Fatalf("want: %v at %v, have: %v", h[i], i, hs[i])
+			}
+		}
+	})
+}
+
+func BenchmarkDHeapPushPop(b *testing.B) {
+	const n = 1 << 16
+	values := make([]int, n)
+	for i := range values {
+		values[i] = rand.Int()
+	}
+
+	b.Run("binary", func(b *testing.B) {
+		h := make([]int, 0, n)
+		for i := 0; i < b.N; i++ {
+			for _, v := range values {
+				HeapPush(&h, v, CompareOrdered[int])
+			}
+			for len(h) > 0 {
+				_ = HeapPop(&h, CompareOrdered[int])
+			}
+		}
+	})
+
+	for _, d := range []int{2, 4, 8} {
+		d := d
+		b.Run(fmt.Sprintf("d=%d", d), func(b *testing.B) {
+			h := make([]int, 0, n)
+			for i := 0; i < b.N; i++ {
+				for _, v := range values {
+					DHeapPush(&h, v, d, CompareOrdered[int])
+				}
+				for len(h) > 0 {
+					_ = DHeapPop(&h, d, CompareOrdered[int])
+				}
+			}
+		})
+	}
+}
@@ -2,4 +2,8 @@ package sly
 
 import "errors"
 
-var ErrBadOptions = errors.New("bad options")
+var (
+	ErrBadOptions = errors.New("bad options")
+	ErrFull       = errors.New("full")
+	ErrClosed     = errors.New("closed")
+)
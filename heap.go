@@ -11,7 +11,7 @@ func HeapPush[T any](heapPtr *[]T, x T, compare Compare[T]) []T {
 	heap := *heapPtr
 
 	heap = append(heap, x)
-	heapSiftUp(heap, compare, len(heap)-1)
+	heapSiftUp(heap, compare, SliceSwap[T], len(heap)-1)
 
 	*heapPtr = heap
 	return heap
@@ -29,28 +29,32 @@ func HeapPop[T any](heapPtr *[]T, compare Compare[T]) T {
 	max := heap[0]
 	SliceSwap(heap, 0, len(heap)-1)
 	heap = heap[:len(heap)-1]
-	heapSiftDown(heap, compare, 0)
+	heapSiftDown(heap, compare, SliceSwap[T], 0)
 
 	*heapPtr = heap
 	return max
 }
 
-// HeapPopTail pops the smallest value from the heap and places it on top.
+// HeapPopTail pops the max value from the heap and places it right
+// past the heap end, which is how SortHeap sorts in place.
+//
+// The last value of the binary heap isn't necessarily the smallest one,
+// see MinMaxHeapPopMin for that.
 //
 //	heapPtr: Heap pointer.
 //	compare: Comparator function.
 //
-// Returns the smallest value.
+// Returns the max value.
 func HeapPopTail[T any](heapPtr *[]T, compare Compare[T]) T {
 	heap := *heapPtr
 
-	min := heap[len(heap)-1]
 	SliceSwap(heap, 0, len(heap)-1)
+	max := heap[len(heap)-1]
 	heap = heap[:len(heap)-1]
-	heapSiftDown(heap, compare, 0)
+	heapSiftDown(heap, compare, SliceSwap[T], 0)
 
 	*heapPtr = heap
-	return min
+	return max
 }
 
 // Heapify makes its input a heap.
@@ -58,11 +62,7 @@ func HeapPopTail[T any](heapPtr *[]T, compare Compare[T]) T {
 //	input: Slice to heapify.
 //	compare: Comparator function.
 func Heapify[T any](input []T, compare Compare[T]) {
-	// Any index beyond len(input)/2 will be a leaf node.
-	for i := len(input)>>1 + 1; i >= 1; {
-		i--
-		heapSiftDown(input, compare, i)
-	}
+	heapify(input, compare, SliceSwap[T])
 }
 
 // SortHeap is an in-place heap sort.
@@ -78,7 +78,19 @@ func SortHeap[T any](input []T, compare Compare[T]) {
 	}
 }
 
-func heapSiftUp[T any](heap []T, compare Compare[T], current int) {
+// heapSwap swaps two heap items, see SliceSwap.
+// Lets the caller keep track of the item indices.
+type heapSwap[T any] func(heap []T, i, j int)
+
+func heapify[T any](input []T, compare Compare[T], swap heapSwap[T]) {
+	// Any index beyond len(input)/2 will be a leaf node.
+	for i := len(input)>>1 + 1; i >= 1; {
+		i--
+		heapSiftDown(input, compare, swap, i)
+	}
+}
+
+func heapSiftUp[T any](heap []T, compare Compare[T], swap heapSwap[T], current int) {
 	for {
 		if current == 0 {
 			break
@@ -90,12 +102,12 @@ func heapSiftUp[T any](heap []T, compare Compare[T], current int) {
 			break
 		}
 
-		SliceSwap(heap, current, parent)
+		swap(heap, current, parent)
 		current = parent
 	}
 }
 
-func heapSiftDown[T any](heap []T, compare Compare[T], current int) {
+func heapSiftDown[T any](heap []T, compare Compare[T], swap heapSwap[T], current int) {
 	n := len(heap) - 1
 	for {
 		// Naively pick the left one.
@@ -117,7 +129,7 @@ func heapSiftDown[T any](heap []T, compare Compare[T], current int) {
 			return
 		}
 
-		SliceSwap(heap, current, maxChild)
+		swap(heap, current, maxChild)
 		current = maxChild
 	}
 }
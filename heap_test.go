@@ -1,6 +1,7 @@
 package sly
 
 import (
+	"github.com/stretchr/testify/assert"
 	"sort"
 	"testing"
 )
@@ -32,6 +33,16 @@ func FuzzHeapPushPop(f *testing.F) {
 	})
 }
 
+func TestHeapPopTail(t *testing.T) {
+	h := []int{1, 3, 2}
+	Heapify(h, CompareOrdered[int])
+
+	heap := h
+	assert.Equal(t, 3, HeapPopTail(&heap, CompareOrdered[int]))
+	assert.Len(t, heap, 2)
+	assert.Equal(t, 3, h[2])
+}
+
 func FuzzSortHeap(f *testing.F) {
 	f.Add([]byte{100, 48, 3, 82, 56, 62, 71, 39, 42, 22})
 	f.Add([]byte(nil))
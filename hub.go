@@ -0,0 +1,297 @@
+package sly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type (
+	// HubOptions are used to construct a new hub.
+	//
+	//  Match: Reports whether the pattern matches the topic, see
+	//   HubMatchPrefix. If nil, then patterns are not supported.
+	//  Sinks: Initial capacity for the sinks of every topic.
+	//  Replay: Number of the last values replayed to the new topic
+	//   subscribers, see NewChanBroadcastReplay.
+	HubOptions[K comparable] struct {
+		Match  func(pattern, topic K) bool
+		Sinks  uint
+		Replay uint
+	}
+
+	// Hub is a topic-based pub/sub built on ChanBroadcast. Topics
+	// are created on the first subscription and torn down once
+	// the last sink is closed.
+	//
+	// A sink must only be subscribed to one topic or pattern
+	// at a time, as each of them closes its sinks.
+	Hub[K comparable, T any] struct {
+		ctx  context.Context
+		opts HubOptions[K]
+
+		mu       sync.RWMutex
+		topics   map[K]*hubTopic[T]
+		patterns map[K]*hubTopic[T]
+	}
+
+	hubTopic[T any] struct {
+		source    chan T
+		broadcast *ChanBroadcast[T]
+		cancel    context.CancelFunc
+		sinks     map[chan<- T]struct{}
+	}
+)
+
+// NewHub creates a new hub.
+//
+//	ctx: Cancellation context, closes the hub once done.
+//	 If nil, defaults to context.Background().
+//	opts: See HubOptions.
+func NewHub[K comparable, T any](ctx context.Context, opts HubOptions[K]) *Hub[K, T] {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &Hub[K, T]{
+		ctx:      ctx,
+		opts:     opts,
+		topics:   make(map[K]*hubTopic[T]),
+		patterns: make(map[K]*hubTopic[T]),
+	}
+}
+
+// HubMatchPrefix matches the string topics. A pattern ending with
+// '*' matches the topics starting with the preceding prefix, other
+// patterns match the equal topics only.
+func HubMatchPrefix(pattern, topic string) bool {
+	if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+		return strings.HasPrefix(topic, prefix)
+	}
+	return pattern == topic
+}
+
+// Subscribe registers the sink to receive the topic values.
+//
+// This is a convenience function for SubscribeOptions with
+// the default options.
+//
+// See SubscribeOptions for more details.
+func (h *Hub[K, T]) Subscribe(ctx context.Context, topic K, sink chan<- T) error {
+	return h.SubscribeOptions(ctx, topic, sink, ChanBroadcastSubOptions[T]{})
+}
+
+// SubscribeOptions registers the sink to receive the topic values.
+//
+//	ctx: Subscription context, see ChanBroadcast.AddOptions.
+//	 If nil, defaults to context.Background().
+//	topic: Topic to subscribe to, created if needed.
+//	sink: Sink channel.
+//	opts: See ChanBroadcastSubOptions.
+//
+// Returns ErrClosed if the hub is closed, or an error if
+// the options are invalid.
+func (h *Hub[K, T]) SubscribeOptions(
+	ctx context.Context,
+	topic K,
+	sink chan<- T,
+	opts ChanBroadcastSubOptions[T],
+) error {
+	return h.subscribe(ctx, h.topics, topic, sink, opts)
+}
+
+// SubscribePattern registers the sink to receive the values
+// of every topic matching the pattern.
+//
+// This is a convenience function for SubscribePatternOptions
+// with the default options.
+//
+// See SubscribePatternOptions for more details.
+func (h *Hub[K, T]) SubscribePattern(ctx context.Context, pattern K, sink chan<- T) error {
+	return h.SubscribePatternOptions(ctx, pattern, sink, ChanBroadcastSubOptions[T]{})
+}
+
+// SubscribePatternOptions registers the sink to receive the values
+// of every topic matching the pattern, see HubOptions.Match.
+//
+//	ctx: Subscription context, see ChanBroadcast.AddOptions.
+//	 If nil, defaults to context.Background().
+//	pattern: Topic pattern.
+//	sink: Sink channel.
+//	opts: See ChanBroadcastSubOptions.
+//
+// Returns ErrClosed if the hub is closed, ErrBadOptions if
+// the hub doesn't support patterns, or an error if the options
+// are invalid.
+func (h *Hub[K, T]) SubscribePatternOptions(
+	ctx context.Context,
+	pattern K,
+	sink chan<- T,
+	opts ChanBroadcastSubOptions[T],
+) error {
+	if h.opts.Match == nil {
+		return fmt.Errorf("%w: nil matcher", ErrBadOptions)
+	}
+	return h.subscribe(ctx, h.patterns, pattern, sink, opts)
+}
+
+// Unsubscribe closes and deletes the sink subscribed to the topic
+// or pattern. The canceled subscriptions are deleted lazily on the
+// next publish, this one takes effect right away.
+//
+//	ctx: Cancellation context. If nil, defaults to context.Background().
+//	key: Topic or pattern the sink is subscribed to.
+//	sink: Sink channel.
+//
+// If the sink is not subscribed, the call is ignored.
+func (h *Hub[K, T]) Unsubscribe(ctx context.Context, key K, sink chan<- T) {
+	h.mu.RLock()
+	var t *hubTopic[T]
+	for _, topics := range [...]map[K]*hubTopic[T]{h.topics, h.patterns} {
+		if candidate, ok := topics[key]; ok {
+			if _, ok = candidate.sinks[sink]; ok {
+				t = candidate
+				break
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	if t != nil {
+		t.broadcast.DeleteContext(ctx, sink)
+	}
+}
+
+// Publish broadcasts the value to the topic subscribers, including
+// the pattern ones. The value is discarded if there are none.
+//
+//	ctx: Cancellation context. If nil, defaults to context.Background().
+//	topic: Topic to publish to.
+//	value: Value to publish.
+//
+// The value is sent to every matching topic and pattern, even if
+// some of the sends fail. Returns ErrClosed if the hub is closed,
+// or the context error if it was canceled while publishing.
+func (h *Hub[K, T]) Publish(ctx context.Context, topic K, value T) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Not holding the lock while sending, as a broadcaster
+	// might be blocked by its sinks, see ChanBroadcastBlock.
+	h.mu.RLock()
+	if h.ctx.Err() != nil {
+		h.mu.RUnlock()
+		return ErrClosed
+	}
+	var targets []*hubTopic[T]
+	if t, ok := h.topics[topic]; ok {
+		targets = append(targets, t)
+	}
+	if h.opts.Match != nil {
+		for pattern, t := range h.patterns {
+			if h.opts.Match(pattern, topic) {
+				targets = append(targets, t)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	var first error
+	for _, t := range targets {
+		err := t.publish(ctx, value)
+		// Torn down meanwhile, nobody to deliver to.
+		if errors.Is(err, ErrClosed) && h.ctx.Err() == nil {
+			err = nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Len returns the number of live topics and patterns.
+func (h *Hub[K, T]) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics) + len(h.patterns)
+}
+
+func (h *Hub[K, T]) subscribe(
+	ctx context.Context,
+	topics map[K]*hubTopic[T],
+	key K,
+	sink chan<- T,
+	opts ChanBroadcastSubOptions[T],
+) error {
+	h.mu.Lock()
+	if h.ctx.Err() != nil {
+		h.mu.Unlock()
+		return ErrClosed
+	}
+
+	t, ok := topics[key]
+	if !ok {
+		accept, cancel := context.WithCancel(h.ctx)
+		t = &hubTopic[T]{
+			source: make(chan T),
+			cancel: cancel,
+			sinks:  make(map[chan<- T]struct{}, h.opts.Sinks),
+		}
+		t.broadcast = NewChanBroadcastReplay(accept, t.source, h.opts.Sinks, h.opts.Replay)
+		topics[key] = t
+	}
+
+	// The broadcaster calls it on its goroutine, must not block.
+	onClose := opts.OnClose
+	opts.OnClose = func(err error) {
+		if onClose != nil {
+			onClose(err)
+		}
+		go h.release(topics, key, t, sink)
+	}
+
+	// Replacing the subscription doesn't add a sink. The sink
+	// keeps the topic alive until it's either added or released.
+	_, replaced := t.sinks[sink]
+	t.sinks[sink] = struct{}{}
+	h.mu.Unlock()
+
+	// Not holding the lock while adding, same as Publish.
+	added, err := t.broadcast.addOptions(ctx, sink, opts)
+	if !added && !replaced {
+		h.release(topics, key, t, sink)
+	}
+	return err
+}
+
+func (h *Hub[K, T]) release(topics map[K]*hubTopic[T], key K, t *hubTopic[T], sink chan<- T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.releaseLF(topics, key, t, sink)
+}
+
+// releaseLF deletes the sink, tearing the topic down if it was the last one.
+func (h *Hub[K, T]) releaseLF(topics map[K]*hubTopic[T], key K, t *hubTopic[T], sink chan<- T) {
+	delete(t.sinks, sink)
+	// The topic might have been recreated already.
+	if len(t.sinks) > 0 || topics[key] != t {
+		return
+	}
+
+	delete(topics, key)
+	t.cancel()
+}
+
+func (t *hubTopic[T]) publish(ctx context.Context, value T) error {
+	select {
+	case t.source <- value:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.broadcast.done:
+		return ErrClosed
+	}
+}
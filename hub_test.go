@@ -0,0 +1,197 @@
+package sly
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHub(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		h := NewHub[string, int](nil, HubOptions[string]{})
+
+		a, b := make(chan int, 10), make(chan int, 10)
+		assert.NoError(t, h.Subscribe(nil, "a", a))
+		assert.NoError(t, h.Subscribe(nil, "b", b))
+		assert.Equal(t, 2, h.Len())
+
+		assert.NoError(t, h.Publish(nil, "a", 1))
+		assert.NoError(t, h.Publish(nil, "b", 2))
+		// No subscribers, discarded.
+		assert.NoError(t, h.Publish(nil, "c", 3))
+
+		assert.Equal(t, 1, <-a)
+		assert.Equal(t, 2, <-b)
+	})
+
+	t.Run("pattern", func(t *testing.T) {
+		h := NewHub[string, int](nil, HubOptions[string]{Match: HubMatchPrefix})
+
+		all, exact := make(chan int, 10), make(chan int, 10)
+		assert.NoError(t, h.SubscribePattern(nil, "a.*", all))
+		assert.NoError(t, h.SubscribePattern(nil, "a.b", exact))
+
+		for i, topic := range []string{"a.b", "a.c", "b.a"} {
+			assert.NoError(t, h.Publish(nil, topic, i))
+		}
+
+		h.Unsubscribe(nil, "a.*", all)
+		h.Unsubscribe(nil, "a.b", exact)
+		assert.Equal(t, []int{0, 1}, chanBroadcastCollect(all))
+		assert.Equal(t, []int{0}, chanBroadcastCollect(exact))
+	})
+
+	t.Run("pattern options", func(t *testing.T) {
+		h := NewHub[string, int](nil, HubOptions[string]{Match: HubMatchPrefix})
+
+		evens := make(chan int, 10)
+		assert.NoError(t, h.SubscribePatternOptions(nil, "*", evens, ChanBroadcastSubOptions[int]{
+			Filter: func(value int) bool { return value%2 == 0 },
+		}))
+		for i, topic := range []string{"a", "b", "c"} {
+			assert.NoError(t, h.Publish(nil, topic, i))
+		}
+
+		h.Unsubscribe(nil, "*", evens)
+		assert.Equal(t, []int{0, 2}, chanBroadcastCollect(evens))
+
+		err := h.SubscribePatternOptions(nil, "*", make(chan int), ChanBroadcastSubOptions[int]{Policy: -1})
+		assert.ErrorIs(t, err, ErrBadOptions)
+		assert.Eventually(t, func() bool { return h.Len() == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("pattern unsupported", func(t *testing.T) {
+		h := NewHub[string, int](nil, HubOptions[string]{})
+		err := h.SubscribePattern(nil, "a.*", make(chan int))
+		assert.ErrorIs(t, err, ErrBadOptions)
+		assert.Equal(t, 0, h.Len())
+	})
+
+	t.Run("teardown", func(t *testing.T) {
+		h := NewHub[string, int](nil, HubOptions[string]{Replay: 1})
+
+		a, b := make(chan int, 10), make(chan int, 10)
+		assert.NoError(t, h.Subscribe(nil, "a", a))
+		assert.NoError(t, h.Subscribe(nil, "a", b))
+		assert.NoError(t, h.Publish(nil, "a", 1))
+
+		h.Unsubscribe(nil, "a", a)
+		assert.Equal(t, []int{1}, chanBroadcastCollect(a))
+		assert.Equal(t, 1, h.Len())
+
+		h.Unsubscribe(nil, "a", b)
+		assert.Equal(t, []int{1}, chanBroadcastCollect(b))
+		assert.Eventually(t, func() bool { return h.Len() == 0 }, time.Second, time.Millisecond)
+
+		// The topic is recreated from scratch, nothing to replay.
+		c := make(chan int, 10)
+		assert.NoError(t, h.Subscribe(nil, "a", c))
+		assert.NoError(t, h.Publish(nil, "a", 2))
+		assert.Equal(t, 2, <-c)
+	})
+
+	t.Run("teardown canceled", func(t *testing.T) {
+		h := NewHub[string, int](nil, HubOptions[string]{})
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		assert.NoError(t, h.Subscribe(ctx, "a", make(chan int)))
+		assert.Equal(t, 0, h.Len())
+
+		ctx, cancel = context.WithCancel(context.TODO())
+		sink := make(chan int, 1)
+		assert.NoError(t, h.Subscribe(ctx, "a", sink))
+		cancel()
+
+		// The cancellation is noticed on publish.
+		assert.NoError(t, h.Publish(nil, "a", 1))
+		_, more := <-sink
+		assert.False(t, more)
+		assert.Eventually(t, func() bool { return h.Len() == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		h := NewHub[string, int](ctx, HubOptions[string]{})
+
+		sink := make(chan int)
+		assert.NoError(t, h.Subscribe(nil, "a", sink))
+		cancel()
+
+		_, more := <-sink
+		assert.False(t, more)
+		assert.ErrorIs(t, h.Subscribe(nil, "a", make(chan int)), ErrClosed)
+		assert.ErrorIs(t, h.Publish(nil, "a", 1), ErrClosed)
+		assert.Eventually(t, func() bool { return h.Len() == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("close unread", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		h := NewHub[string, int](ctx, HubOptions[string]{})
+
+		sink := make(chan int)
+		assert.NoError(t, h.SubscribeOptions(nil, "a", sink, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastDropOldest,
+		}))
+		assert.NoError(t, h.Publish(nil, "a", 1))
+		assert.NoError(t, h.Publish(nil, "a", 2))
+		cancel()
+
+		// The topic is torn down with the sink left unread.
+		assert.Eventually(t, func() bool { return h.Len() == 0 }, time.Second, time.Millisecond)
+		chanBroadcastCollect(sink)
+	})
+
+	t.Run("publish blocked", func(t *testing.T) {
+		h := NewHub[string, int](nil, HubOptions[string]{})
+
+		stuck := make(chan int)
+		assert.NoError(t, h.SubscribeOptions(nil, "a", stuck, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastBlock,
+		}))
+		// Blocks the broadcaster, then the publisher.
+		assert.NoError(t, h.Publish(nil, "a", 1))
+		ctx, cancel := context.WithCancel(context.TODO())
+		published := make(chan error)
+		go func() { published <- h.Publish(ctx, "a", 2) }()
+
+		// Giving the publisher time to block.
+		time.Sleep(10 * time.Millisecond)
+
+		// The other topics must not be affected.
+		sink := make(chan int, 1)
+		assert.NoError(t, h.Subscribe(nil, "b", sink))
+		assert.NoError(t, h.Publish(nil, "b", 3))
+		assert.Equal(t, 3, <-sink)
+		assert.Equal(t, 2, h.Len())
+
+		cancel()
+		assert.ErrorIs(t, <-published, context.Canceled)
+		assert.Equal(t, 1, <-stuck)
+	})
+
+	t.Run("publish cancel", func(t *testing.T) {
+		h := NewHub[string, int](nil, HubOptions[string]{})
+
+		sink := make(chan int)
+		assert.NoError(t, h.SubscribeOptions(nil, "a", sink, ChanBroadcastSubOptions[int]{
+			Policy: ChanBroadcastBlock,
+		}))
+		// Blocks the broadcaster, as nobody is receiving.
+		assert.NoError(t, h.Publish(nil, "a", 1))
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		assert.ErrorIs(t, h.Publish(ctx, "a", 2), context.Canceled)
+		assert.Equal(t, 1, <-sink)
+	})
+}
+
+func TestHubMatchPrefix(t *testing.T) {
+	assert.True(t, HubMatchPrefix("a.*", "a.b"))
+	assert.True(t, HubMatchPrefix("*", "a"))
+	assert.True(t, HubMatchPrefix("a", "a"))
+	assert.False(t, HubMatchPrefix("a.*", "b.a"))
+	assert.False(t, HubMatchPrefix("a", "a.b"))
+}
@@ -0,0 +1,169 @@
+package sly
+
+import "math/bits"
+
+// MinMaxHeapPush pushes the value onto the min-max heap.
+//
+//	heapPtr: Min-max heap pointer.
+//	x: Value to push.
+//	compare: Comparator function.
+//
+// Returns the min-max heap with the value pushed onto it.
+func MinMaxHeapPush[T any](heapPtr *[]T, x T, compare Compare[T]) []T {
+	heap := *heapPtr
+
+	heap = append(heap, x)
+	minMaxHeapSiftUp(heap, compare, len(heap)-1)
+
+	*heapPtr = heap
+	return heap
+}
+
+// MinMaxHeapPopMax pops the max value from the min-max heap.
+//
+//	heapPtr: Min-max heap pointer.
+//	compare: Comparator function.
+//
+// Returns the max value.
+func MinMaxHeapPopMax[T any](heapPtr *[]T, compare Compare[T]) T {
+	return minMaxHeapRemove(heapPtr, compare, 0)
+}
+
+// MinMaxHeapPopMin pops the min value from the min-max heap.
+//
+//	heapPtr: Min-max heap pointer.
+//	compare: Comparator function.
+//
+// Returns the min value.
+func MinMaxHeapPopMin[T any](heapPtr *[]T, compare Compare[T]) T {
+	heap := *heapPtr
+
+	// The min is one of the root children, if there are any.
+	min := 0
+	switch {
+	case len(heap) > 2 && compare.Less(heap[2], heap[1]):
+		min = 2
+	case len(heap) > 1:
+		min = 1
+	}
+	return minMaxHeapRemove(heapPtr, compare, min)
+}
+
+// MinMaxHeapify makes its input a min-max heap.
+//
+//	input: Slice to heapify.
+//	compare: Comparator function.
+func MinMaxHeapify[T any](input []T, compare Compare[T]) {
+	reverse := CompareReverse(compare)
+	// Any index beyond len(input)/2 will be a leaf node.
+	for i := len(input)>>1 + 1; i >= 1; {
+		i--
+		if minMaxHeapIsMaxLevel(i) {
+			minMaxHeapSiftDown(input, compare, i)
+		} else {
+			minMaxHeapSiftDown(input, reverse, i)
+		}
+	}
+}
+
+func minMaxHeapRemove[T any](heapPtr *[]T, compare Compare[T], i int) T {
+	heap := *heapPtr
+
+	x := heap[i]
+	SliceSwap(heap, i, len(heap)-1)
+	heap = heap[:len(heap)-1]
+	if i < len(heap) {
+		if minMaxHeapIsMaxLevel(i) {
+			minMaxHeapSiftDown(heap, compare, i)
+		} else {
+			minMaxHeapSiftDown(heap, CompareReverse(compare), i)
+		}
+	}
+
+	*heapPtr = heap
+	return x
+}
+
+// minMaxHeapIsMaxLevel reports whether i is on the max level.
+// The root level is a max one, then the levels alternate.
+func minMaxHeapIsMaxLevel(i int) bool {
+	return bits.Len(uint(i+1))&1 == 1
+}
+
+func minMaxHeapSiftUp[T any](heap []T, compare Compare[T], current int) {
+	if current == 0 {
+		return
+	}
+
+	// Sifting up along the levels of the same kind as `current`,
+	// unless it belongs to the levels of the parent's kind.
+	if !minMaxHeapIsMaxLevel(current) {
+		compare = CompareReverse(compare)
+	}
+	parent := (current - 1) >> 1
+	if compare.Less(heap[current], heap[parent]) {
+		SliceSwap(heap, current, parent)
+		current = parent
+		compare = CompareReverse(compare)
+	}
+
+	// `compare` is max-like for the `current` level now.
+	for current > 2 {
+		grandparent := ((current-1)>>1 - 1) >> 1
+		// Stop if `current` is on its place.
+		if compare.LessOrEqual(heap[current], heap[grandparent]) {
+			return
+		}
+
+		SliceSwap(heap, current, grandparent)
+		current = grandparent
+	}
+}
+
+// minMaxHeapSiftDown expects the compare to be max-like
+// for the `current` level.
+func minMaxHeapSiftDown[T any](heap []T, compare Compare[T], current int) {
+	n := len(heap)
+	for {
+		firstChild := current<<1 + 1
+		// Stop if `current` is a leaf.
+		if firstChild >= n {
+			return
+		}
+
+		// Picking the one with the highest priority out of
+		// the children and grandchildren.
+		max := firstChild
+		candidates := [...]int{
+			firstChild + 1,
+			firstChild<<1 + 1,
+			firstChild<<1 + 2,
+			(firstChild+1)<<1 + 1,
+			(firstChild+1)<<1 + 2,
+		}
+		for _, i := range candidates {
+			if i < n && compare.Greater(heap[i], heap[max]) {
+				max = i
+			}
+		}
+
+		// Stop if `current` is on its place.
+		if compare.GreaterOrEqual(heap[current], heap[max]) {
+			return
+		}
+		SliceSwap(heap, current, max)
+
+		// Child has no descendants greater than itself, done.
+		if max <= firstChild+1 {
+			return
+		}
+
+		// Grandchild's parent is on the opposite level,
+		// the former `current` might belong to it.
+		parent := (max - 1) >> 1
+		if compare.Less(heap[max], heap[parent]) {
+			SliceSwap(heap, max, parent)
+		}
+		current = max
+	}
+}
@@ -0,0 +1,61 @@
+package sly
+
+import (
+	"sort"
+	"testing"
+)
+
+func FuzzMinMaxHeapPushPop(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49})
+	f.Add([]byte(nil))
+	f.Fuzz(func(t *testing.T, bs []byte) {
+		h := make([]int, 0, len(bs))
+		for _, val := range bs {
+			MinMaxHeapPush(&h, int(val), CompareOrdered[int])
+		}
+
+		want := make([]int, len(bs))
+		for i, val := range bs {
+			want[i] = int(val)
+		}
+		sort.Ints(want)
+
+		// Popping both ends in turns.
+		lo, hi := 0, len(want)-1
+		for i := 0; len(h) > 0; i++ {
+			var have, wantValue int
+			if i%2 == 0 {
+				have, wantValue = MinMaxHeapPopMax(&h, CompareOrdered[int]), want[hi]
+				hi--
+			} else {
+				have, wantValue = MinMaxHeapPopMin(&h, CompareOrdered[int]), want[lo]
+				lo++
+			}
+			if have != wantValue {
+				t.Fatalf("want: %v at %v, have: %v", wantValue, i, have)
+			}
+		}
+	})
+}
+
+func FuzzMinMaxHeapify(f *testing.F) {
+	f.Add([]byte{100, 48, 3, 82, 56, 62, 71, 39, 42, 22})
+	f.Add([]byte(nil))
+	f.Fuzz(func(t *testing.T, bs []byte) {
+		h := make([]int, 0, len(bs))
+		for _, val := range bs {
+			h = append(h, int(val))
+		}
+		MinMaxHeapify(h, CompareOrdered[int])
+
+		want := append([]int(nil), h...)
+		sort.Ints(want)
+
+		for i := range want {
+			have := MinMaxHeapPopMin(&h, CompareOrdered[int])
+			if have != want[i] {
+				t.Fatalf("want: %v at %v, have: %v", want[i], i, have)
+			}
+		}
+	})
+}
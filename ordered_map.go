@@ -0,0 +1,554 @@
+package sly
+
+// Minimum degree of the OrderedMap B-tree. The nodes other than
+// the root hold from degree-1 to 2*degree-1 entries.
+const orderedMapDegree = 16
+
+const (
+	orderedMapMinEntries = orderedMapDegree - 1
+	orderedMapMaxEntries = 2*orderedMapDegree - 1
+)
+
+type (
+	// OrderedMap is a B-tree based map, which keeps its keys ordered.
+	// Use struct{} values for a sorted set.
+	//
+	// OrderedMap isn't thread-safe.
+	OrderedMap[K, V any] struct {
+		root    *orderedMapNode[K, V]
+		compare Compare[orderedMapEntry[K, V]]
+		len     int
+	}
+
+	orderedMapNode[K, V any] struct {
+		entries []orderedMapEntry[K, V]
+		// Nil for the leaves.
+		children []*orderedMapNode[K, V]
+	}
+
+	orderedMapEntry[K, V any] struct {
+		key   K
+		value V
+	}
+
+	orderedMapRemove int
+)
+
+const (
+	orderedMapRemoveKey orderedMapRemove = iota
+	orderedMapRemoveMax
+)
+
+// NewOrderedMap creates a new ordered map.
+//
+//	compare: Key comparator function.
+func NewOrderedMap[K, V any](compare Compare[K]) *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		compare: func(a, b orderedMapEntry[K, V]) int {
+			return compare(a.key, b.key)
+		},
+	}
+}
+
+// Len returns the number of entries in the map.
+func (m *OrderedMap[K, V]) Len() int {
+	return m.len
+}
+
+// Get looks up the key.
+//
+//	key: Key to look up.
+//
+// Returns the key value, or the default value and false
+// if there's no such key.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	target := orderedMapEntry[K, V]{key: key}
+	for n := m.root; n != nil; {
+		i, found := BinarySearch(n.entries, target, m.compare)
+		if found {
+			return n.entries[i].value, true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+
+	var z V
+	return z, false
+}
+
+// Put sets the key value.
+//
+//	key: Key to set.
+//	value: Value to set.
+//
+// Returns true if the key has been added, or false
+// if the value of the existing key has been replaced.
+func (m *OrderedMap[K, V]) Put(key K, value V) bool {
+	entry := orderedMapEntry[K, V]{key: key, value: value}
+	if m.root == nil {
+		m.root = &orderedMapNode[K, V]{
+			entries: append(make([]orderedMapEntry[K, V], 0, orderedMapMaxEntries), entry),
+		}
+		m.len++
+		return true
+	}
+
+	// Growing the tree at the root.
+	if len(m.root.entries) == orderedMapMaxEntries {
+		root := &orderedMapNode[K, V]{
+			entries:  make([]orderedMapEntry[K, V], 0, orderedMapMaxEntries),
+			children: append(make([]*orderedMapNode[K, V], 0, orderedMapMaxEntries+1), m.root),
+		}
+		root.split(0)
+		m.root = root
+	}
+
+	if !m.root.insert(entry, m.compare) {
+		return false
+	}
+	m.len++
+	return true
+}
+
+// Delete deletes the key.
+//
+//	key: Key to delete.
+//
+// Returns the deleted key value, or the default value and false
+// if there's no such key.
+func (m *OrderedMap[K, V]) Delete(key K) (V, bool) {
+	var z V
+	if m.root == nil {
+		return z, false
+	}
+
+	entry, ok := m.root.remove(orderedMapEntry[K, V]{key: key}, orderedMapRemoveKey, m.compare)
+	// Shrinking the tree at the root.
+	if len(m.root.entries) == 0 {
+		if m.root.leaf() {
+			m.root = nil
+		} else {
+			m.root = m.root.children[0]
+		}
+	}
+
+	if !ok {
+		return z, false
+	}
+	m.len--
+	return entry.value, true
+}
+
+// Min looks up the smallest key.
+//
+// Returns the smallest key and its value, or the default values
+// and false if the map is empty.
+func (m *OrderedMap[K, V]) Min() (K, V, bool) {
+	n := m.root
+	if n == nil {
+		var (
+			zk K
+			zv V
+		)
+		return zk, zv, false
+	}
+
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	entry := n.entries[0]
+	return entry.key, entry.value, true
+}
+
+// Max looks up the greatest key.
+//
+// Returns the greatest key and its value, or the default values
+// and false if the map is empty.
+func (m *OrderedMap[K, V]) Max() (K, V, bool) {
+	n := m.root
+	if n == nil {
+		var (
+			zk K
+			zv V
+		)
+		return zk, zv, false
+	}
+
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	entry := n.entries[len(n.entries)-1]
+	return entry.key, entry.value, true
+}
+
+// Floor looks up the greatest key less than or equal to the key.
+//
+//	key: Key to look up.
+//
+// Returns the key found and its value, or the default values
+// and false if there's none.
+func (m *OrderedMap[K, V]) Floor(key K) (K, V, bool) {
+	var floor *orderedMapEntry[K, V]
+	target := orderedMapEntry[K, V]{key: key}
+	for n := m.root; n != nil; {
+		i := UpperBound(n.entries, target, m.compare)
+		if i > 0 {
+			floor = &n.entries[i-1]
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+
+	if floor == nil {
+		var (
+			zk K
+			zv V
+		)
+		return zk, zv, false
+	}
+	return floor.key, floor.value, true
+}
+
+// Ceiling looks up the smallest key greater than or equal to the key.
+//
+//	key: Key to look up.
+//
+// Returns the key found and its value, or the default values
+// and false if there's none.
+func (m *OrderedMap[K, V]) Ceiling(key K) (K, V, bool) {
+	var ceiling *orderedMapEntry[K, V]
+	target := orderedMapEntry[K, V]{key: key}
+	for n := m.root; n != nil; {
+		i := LowerBound(n.entries, target, m.compare)
+		if i < len(n.entries) {
+			ceiling = &n.entries[i]
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+
+	if ceiling == nil {
+		var (
+			zk K
+			zv V
+		)
+		return zk, zv, false
+	}
+	return ceiling.key, ceiling.value, true
+}
+
+// Ascend iterates over the map in ascending key order.
+//
+//	fn: Iteration callback, returns false to stop.
+func (m *OrderedMap[K, V]) Ascend(fn func(key K, value V) bool) {
+	var z orderedMapEntry[K, V]
+	m.root.ascend(z, z, false, false, m.compare, fn)
+}
+
+// AscendRange iterates over the map in ascending key order
+// within the [from, to) range.
+//
+//	from: Range start, inclusive.
+//	to: Range end, exclusive.
+//	fn: Iteration callback, returns false to stop.
+func (m *OrderedMap[K, V]) AscendRange(from, to K, fn func(key K, value V) bool) {
+	m.root.ascend(
+		orderedMapEntry[K, V]{key: from},
+		orderedMapEntry[K, V]{key: to},
+		true, true, m.compare, fn,
+	)
+}
+
+// Descend iterates over the map in descending key order.
+//
+//	fn: Iteration callback, returns false to stop.
+func (m *OrderedMap[K, V]) Descend(fn func(key K, value V) bool) {
+	var z orderedMapEntry[K, V]
+	m.root.descend(z, z, false, false, m.compare, fn)
+}
+
+// DescendRange iterates over the map in descending key order
+// within the (to, from] range.
+//
+//	from: Range start, inclusive.
+//	to: Range end, exclusive.
+//	fn: Iteration callback, returns false to stop.
+func (m *OrderedMap[K, V]) DescendRange(from, to K, fn func(key K, value V) bool) {
+	m.root.descend(
+		orderedMapEntry[K, V]{key: from},
+		orderedMapEntry[K, V]{key: to},
+		true, true, m.compare, fn,
+	)
+}
+
+func (n *orderedMapNode[K, V]) leaf() bool {
+	return n.children == nil
+}
+
+// insert inserts the entry into the non-full node subtree.
+//
+// Returns false if the entry has replaced the existing one.
+func (n *orderedMapNode[K, V]) insert(entry orderedMapEntry[K, V], compare Compare[orderedMapEntry[K, V]]) bool {
+	for {
+		i, found := BinarySearch(n.entries, entry, compare)
+		if found {
+			n.entries[i] = entry
+			return false
+		}
+
+		if n.leaf() {
+			n.entries = append(n.entries, orderedMapEntry[K, V]{})
+			copy(n.entries[i+1:], n.entries[i:])
+			n.entries[i] = entry
+			return true
+		}
+
+		// Splitting the full nodes on the way down, so that
+		// there's always room for the median of the child.
+		if len(n.children[i].entries) == orderedMapMaxEntries {
+			n.split(i)
+			switch cmp := compare(entry, n.entries[i]); {
+			case cmp == 0:
+				n.entries[i] = entry
+				return false
+			case cmp > 0:
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// split splits the full i-th child in two around its median.
+func (n *orderedMapNode[K, V]) split(i int) {
+	child := n.children[i]
+	median := child.entries[orderedMapMinEntries]
+
+	right := &orderedMapNode[K, V]{
+		entries: append(
+			make([]orderedMapEntry[K, V], 0, orderedMapMaxEntries),
+			child.entries[orderedMapMinEntries+1:]...,
+		),
+	}
+	// Not retaining the moved entries.
+	var z orderedMapEntry[K, V]
+	for j := orderedMapMinEntries; j < len(child.entries); j++ {
+		child.entries[j] = z
+	}
+	child.entries = child.entries[:orderedMapMinEntries]
+
+	if !child.leaf() {
+		right.children = append(
+			make([]*orderedMapNode[K, V], 0, orderedMapMaxEntries+1),
+			child.children[orderedMapMinEntries+1:]...,
+		)
+		for j := orderedMapMinEntries + 1; j < len(child.children); j++ {
+			child.children[j] = nil
+		}
+		child.children = child.children[:orderedMapMinEntries+1]
+	}
+
+	n.entries = append(n.entries, z)
+	copy(n.entries[i+1:], n.entries[i:])
+	n.entries[i] = median
+
+	n.children = append(n.children, nil)
+	copy(n.children[i+2:], n.children[i+1:])
+	n.children[i+1] = right
+}
+
+// remove removes the entry from the node subtree, which is either
+// the root or has more than the minimum number of entries.
+func (n *orderedMapNode[K, V]) remove(
+	target orderedMapEntry[K, V],
+	typ orderedMapRemove,
+	compare Compare[orderedMapEntry[K, V]],
+) (orderedMapEntry[K, V], bool) {
+	for {
+		var (
+			i     int
+			found bool
+		)
+		switch typ {
+		case orderedMapRemoveMax:
+			i = len(n.entries)
+			if n.leaf() {
+				i--
+				found = true
+			}
+		default:
+			i, found = BinarySearch(n.entries, target, compare)
+		}
+
+		if n.leaf() {
+			if !found {
+				return target, false
+			}
+			entry := n.entries[i]
+			n.removeEntry(i)
+			return entry, true
+		}
+
+		// Making sure there's enough entries in the child
+		// to remove one, starting over if anything has moved.
+		if len(n.children[i].entries) <= orderedMapMinEntries {
+			n.grow(i)
+			continue
+		}
+
+		// Replacing the entry with its predecessor.
+		if found {
+			entry := n.entries[i]
+			n.entries[i], _ = n.children[i].remove(target, orderedMapRemoveMax, compare)
+			return entry, true
+		}
+		n = n.children[i]
+	}
+}
+
+// grow adds an entry to the i-th child, either borrowing it through
+// the parent from a sibling or merging the child with a sibling.
+func (n *orderedMapNode[K, V]) grow(i int) {
+	switch {
+	case i > 0 && len(n.children[i-1].entries) > orderedMapMinEntries:
+		child, left := n.children[i], n.children[i-1]
+
+		child.entries = append(child.entries, orderedMapEntry[K, V]{})
+		copy(child.entries[1:], child.entries)
+		child.entries[0] = n.entries[i-1]
+		n.entries[i-1] = left.entries[len(left.entries)-1]
+		left.removeEntry(len(left.entries) - 1)
+
+		if !left.leaf() {
+			child.children = append(child.children, nil)
+			copy(child.children[1:], child.children)
+			child.children[0] = left.children[len(left.children)-1]
+			left.removeChild(len(left.children) - 1)
+		}
+
+	case i < len(n.entries) && len(n.children[i+1].entries) > orderedMapMinEntries:
+		child, right := n.children[i], n.children[i+1]
+
+		child.entries = append(child.entries, n.entries[i])
+		n.entries[i] = right.entries[0]
+		right.removeEntry(0)
+
+		if !right.leaf() {
+			child.children = append(child.children, right.children[0])
+			right.removeChild(0)
+		}
+
+	default:
+		if i == len(n.entries) {
+			i--
+		}
+		n.merge(i)
+	}
+}
+
+// merge merges the i-th child, the i-th entry and the next child.
+func (n *orderedMapNode[K, V]) merge(i int) {
+	child, right := n.children[i], n.children[i+1]
+
+	child.entries = append(child.entries, n.entries[i])
+	child.entries = append(child.entries, right.entries...)
+	if !child.leaf() {
+		child.children = append(child.children, right.children...)
+	}
+
+	n.removeEntry(i)
+	n.removeChild(i + 1)
+}
+
+func (n *orderedMapNode[K, V]) removeEntry(i int) {
+	copy(n.entries[i:], n.entries[i+1:])
+	// Not retaining the removed entry.
+	n.entries[len(n.entries)-1] = orderedMapEntry[K, V]{}
+	n.entries = n.entries[:len(n.entries)-1]
+}
+
+func (n *orderedMapNode[K, V]) removeChild(i int) {
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+}
+
+// ascend iterates over the entries in [from, to) ascending.
+//
+// Returns false if the iteration has been stopped.
+func (n *orderedMapNode[K, V]) ascend(
+	from, to orderedMapEntry[K, V],
+	hasFrom, hasTo bool,
+	compare Compare[orderedMapEntry[K, V]],
+	fn func(key K, value V) bool,
+) bool {
+	if n == nil {
+		return true
+	}
+
+	i := 0
+	if hasFrom {
+		i = LowerBound(n.entries, from, compare)
+	}
+	for ; i < len(n.entries); i++ {
+		if !n.leaf() && !n.children[i].ascend(from, to, hasFrom, hasTo, compare, fn) {
+			return false
+		}
+
+		entry := n.entries[i]
+		if hasTo && compare.GreaterOrEqual(entry, to) {
+			return false
+		}
+		if !fn(entry.key, entry.value) {
+			return false
+		}
+	}
+
+	if n.leaf() {
+		return true
+	}
+	return n.children[len(n.entries)].ascend(from, to, hasFrom, hasTo, compare, fn)
+}
+
+// descend iterates over the entries in (to, from] descending.
+//
+// Returns false if the iteration has been stopped.
+func (n *orderedMapNode[K, V]) descend(
+	from, to orderedMapEntry[K, V],
+	hasFrom, hasTo bool,
+	compare Compare[orderedMapEntry[K, V]],
+	fn func(key K, value V) bool,
+) bool {
+	if n == nil {
+		return true
+	}
+
+	i := len(n.entries) - 1
+	if hasFrom {
+		i = UpperBound(n.entries, from, compare) - 1
+	}
+	if !n.leaf() && !n.children[i+1].descend(from, to, hasFrom, hasTo, compare, fn) {
+		return false
+	}
+
+	for ; i >= 0; i-- {
+		entry := n.entries[i]
+		if hasTo && compare.LessOrEqual(entry, to) {
+			return false
+		}
+		if !fn(entry.key, entry.value) {
+			return false
+		}
+
+		if !n.leaf() && !n.children[i].descend(from, to, hasFrom, hasTo, compare, fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,232 @@
+package sly
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		m := NewOrderedMap[int, string](CompareOrdered[int])
+		assert.Equal(t, 0, m.Len())
+
+		_, ok := m.Get(1)
+		assert.False(t, ok)
+		_, ok = m.Delete(1)
+		assert.False(t, ok)
+		_, _, ok = m.Min()
+		assert.False(t, ok)
+		_, _, ok = m.Max()
+		assert.False(t, ok)
+		_, _, ok = m.Floor(1)
+		assert.False(t, ok)
+		_, _, ok = m.Ceiling(1)
+		assert.False(t, ok)
+
+		m.Ascend(func(int, string) bool {
+			t.Fatal("must not be called")
+			return true
+		})
+		m.Descend(func(int, string) bool {
+			t.Fatal("must not be called")
+			return true
+		})
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		m := NewOrderedMap[int, string](CompareOrdered[int])
+		assert.True(t, m.Put(2, "b"))
+		assert.True(t, m.Put(1, "a"))
+		assert.False(t, m.Put(2, "B"))
+		assert.Equal(t, 2, m.Len())
+
+		v, ok := m.Get(2)
+		assert.Equal(t, "B", v)
+		assert.True(t, ok)
+
+		v, ok = m.Delete(1)
+		assert.Equal(t, "a", v)
+		assert.True(t, ok)
+		assert.Equal(t, 1, m.Len())
+
+		_, ok = m.Get(1)
+		assert.False(t, ok)
+	})
+
+	t.Run("bounds", func(t *testing.T) {
+		m := NewOrderedMap[int, int](CompareOrdered[int])
+		for i := 0; i < 1000; i += 10 {
+			m.Put(i, i*2)
+		}
+
+		k, v, ok := m.Min()
+		assert.Equal(t, []interface{}{0, 0, true}, []interface{}{k, v, ok})
+		k, v, ok = m.Max()
+		assert.Equal(t, []interface{}{990, 1980, true}, []interface{}{k, v, ok})
+
+		k, _, ok = m.Floor(55)
+		assert.Equal(t, 50, k)
+		assert.True(t, ok)
+		k, _, _ = m.Floor(50)
+		assert.Equal(t, 50, k)
+		_, _, ok = m.Floor(-1)
+		assert.False(t, ok)
+
+		k, _, ok = m.Ceiling(55)
+		assert.Equal(t, 60, k)
+		assert.True(t, ok)
+		k, _, _ = m.Ceiling(60)
+		assert.Equal(t, 60, k)
+		_, _, ok = m.Ceiling(991)
+		assert.False(t, ok)
+	})
+
+	t.Run("iterate", func(t *testing.T) {
+		m := NewOrderedMap[int, struct{}](CompareOrdered[int])
+		for i := 0; i < 1000; i++ {
+			m.Put(i, struct{}{})
+		}
+
+		var keys []int
+		collect := func(k int, _ struct{}) bool {
+			keys = append(keys, k)
+			return true
+		}
+
+		m.AscendRange(100, 105, collect)
+		assert.Equal(t, []int{100, 101, 102, 103, 104}, keys)
+
+		keys = nil
+		m.DescendRange(105, 100, collect)
+		assert.Equal(t, []int{105, 104, 103, 102, 101}, keys)
+
+		keys = nil
+		m.Ascend(func(k int, _ struct{}) bool {
+			keys = append(keys, k)
+			return len(keys) < 3
+		})
+		assert.Equal(t, []int{0, 1, 2}, keys)
+
+		keys = nil
+		m.Descend(func(k int, _ struct{}) bool {
+			keys = append(keys, k)
+			return len(keys) < 3
+		})
+		assert.Equal(t, []int{999, 998, 997}, keys)
+	})
+}
+
+func FuzzOrderedMap(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49, 140, 168, 12})
+	f.Add([]byte(nil))
+	f.Fuzz(func(t *testing.T, bs []byte) {
+		m := NewOrderedMap[int, int](CompareOrdered[int])
+		want := make(map[int]int)
+
+		// Spreading the keys, so that the tree gets several levels deep.
+		for i, b := range bs {
+			for j := 0; j < 8; j++ {
+				key := int(b&0x7f)*8 + j
+				if b&0x80 != 0 {
+					_, ok := m.Delete(key)
+					_, wantOK := want[key]
+					assert.Equal(t, wantOK, ok)
+					delete(want, key)
+				} else {
+					m.Put(key, i)
+					want[key] = i
+				}
+			}
+		}
+		orderedMapCheck(t, m.root, true)
+
+		keys := make([]int, 0, len(want))
+		for k := range want {
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+		assert.Equal(t, len(keys), m.Len())
+
+		var ascended []int
+		m.Ascend(func(k, v int) bool {
+			assert.Equal(t, want[k], v)
+			ascended = append(ascended, k)
+			return true
+		})
+		if len(keys) == 0 {
+			assert.Empty(t, ascended)
+		} else {
+			assert.Equal(t, keys, ascended)
+		}
+
+		for k, v := range want {
+			have, ok := m.Get(k)
+			assert.True(t, ok)
+			assert.Equal(t, v, have)
+		}
+
+		if len(bs) == 0 {
+			return
+		}
+		from, to := int(bs[0])*4, int(bs[len(bs)-1])*4
+		if from > to {
+			from, to = to, from
+		}
+		lo, hi := LowerBound(keys, from, CompareOrdered[int]), LowerBound(keys, to, CompareOrdered[int])
+
+		var ranged []int
+		m.AscendRange(from, to, func(k, _ int) bool {
+			ranged = append(ranged, k)
+			return true
+		})
+		assert.Equal(t, keys[lo:hi], append([]int{}, ranged...))
+
+		k, _, ok := m.Ceiling(from)
+		assert.Equal(t, lo < len(keys), ok)
+		if ok {
+			assert.Equal(t, keys[lo], k)
+		}
+
+		lo, hi = UpperBound(keys, to, CompareOrdered[int]), UpperBound(keys, from, CompareOrdered[int])
+		ranged = ranged[:0]
+		m.DescendRange(to, from, func(k, _ int) bool {
+			ranged = append(ranged, k)
+			return true
+		})
+		for i := 0; i < len(ranged); i++ {
+			assert.Equal(t, keys[lo-1-i], ranged[i])
+		}
+		assert.Equal(t, lo-hi, len(ranged))
+
+		k, _, ok = m.Floor(to)
+		assert.Equal(t, lo > 0, ok)
+		if ok {
+			assert.Equal(t, keys[lo-1], k)
+		}
+	})
+}
+
+// orderedMapCheck validates the B-tree invariants.
+//
+// Returns the subtree depth.
+func orderedMapCheck[K, V any](t *testing.T, n *orderedMapNode[K, V], root bool) int {
+	if n == nil {
+		return 0
+	}
+
+	if !root {
+		assert.GreaterOrEqual(t, len(n.entries), orderedMapMinEntries)
+	}
+	assert.LessOrEqual(t, len(n.entries), orderedMapMaxEntries)
+	if n.leaf() {
+		return 1
+	}
+
+	assert.Len(t, n.children, len(n.entries)+1)
+	depth := orderedMapCheck(t, n.children[0], false)
+	for _, child := range n.children[1:] {
+		assert.Equal(t, depth, orderedMapCheck(t, child, false))
+	}
+	return depth + 1
+}
@@ -36,3 +36,82 @@ func PartitionFat[T any](x []T, pivot T, compare Compare[T]) (less, greater int)
 	}
 	return
 }
+
+// PartitionDual executes dual-pivot partition of x around the low
+// and high pivots, low <= high.
+//
+//	x: Slice to be partitioned.
+//	low: Low pivot.
+//	high: High pivot.
+//	compare: Comparator function.
+//
+// Returns a pair of indices:
+//
+//	less: all(y < low for y in x[:less]).
+//	greater: all(y > high for y in x[greater+1:]).
+//
+//	all(low <= y <= high for y in x[less:greater+1])
+func PartitionDual[T any](x []T, low, high T, compare Compare[T]) (less, greater int) {
+	less = 0
+	middle := 0
+	greater = len(x) - 1
+
+	// < low (moves right) | in between (moves right) | > high (moves left).
+	for middle <= greater {
+		switch {
+		case compare.Less(x[middle], low):
+			SliceSwap(x, less, middle)
+			less++
+			middle++
+
+		case compare.Greater(x[middle], high):
+			SliceSwap(x, middle, greater)
+			greater--
+
+		default:
+			middle++
+		}
+	}
+	return
+}
+
+// Partition executes two-way partition of x by the predicate.
+// The relative order of the values isn't preserved, see StablePartition.
+//
+//	x: Slice to be partitioned.
+//	pred: Predicate function.
+//
+// Returns an index:
+//
+//	split: all(pred(y) for y in x[:split]).
+//
+//	all(!pred(y) for y in x[split:])
+func Partition[T any](x []T, pred func(T) bool) (split int) {
+	for i := range x {
+		if pred(x[i]) {
+			SliceSwap(x, split, i)
+			split++
+		}
+	}
+	return
+}
+
+// StablePartition executes two-way partition of x by the predicate,
+// preserving the relative order of the values.
+//
+// Allocates a scratch slice for the values not satisfying the predicate.
+//
+// See Partition for more details.
+func StablePartition[T any](x []T, pred func(T) bool) (split int) {
+	var scratch []T
+	for _, y := range x {
+		if pred(y) {
+			x[split] = y
+			split++
+		} else {
+			scratch = append(scratch, y)
+		}
+	}
+	copy(x[split:], scratch)
+	return
+}
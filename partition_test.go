@@ -35,3 +35,77 @@ func TestPartitionFat(t *testing.T) {
 	assert.Equal(t, 1, less)
 	assert.Equal(t, 3, greater)
 }
+
+func FuzzPartitionDual(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49}, byte(20), byte(40))
+	f.Fuzz(func(t *testing.T, bs []byte, low, high byte) {
+		if low > high {
+			low, high = high, low
+		}
+		less, greater := PartitionDual(bs, low, high, CompareOrdered[byte])
+		for _, v := range bs[:less] {
+			assert.Less(t, v, low)
+		}
+		for _, v := range bs[greater+1:] {
+			assert.Greater(t, v, high)
+		}
+		for _, v := range bs[less : greater+1] {
+			assert.GreaterOrEqual(t, v, low)
+			assert.LessOrEqual(t, v, high)
+		}
+	})
+}
+
+func TestPartitionDual(t *testing.T) {
+	less, greater := PartitionDual(nil, 0, 1, CompareOrdered[int])
+	assert.Equal(t, 0, less)
+	assert.Equal(t, -1, greater)
+
+	less, greater = PartitionDual([]int{4, 0, 2, 1, 3}, 1, 3, CompareOrdered[int])
+	assert.Equal(t, 1, less)
+	assert.Equal(t, 3, greater)
+}
+
+func FuzzPartition(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49})
+	f.Fuzz(func(t *testing.T, bs []byte) {
+		odd := func(b byte) bool {
+			return b&1 == 1
+		}
+
+		stable := append(make([]byte, 0, len(bs)), bs...)
+		split := Partition(bs, odd)
+		for _, v := range bs[:split] {
+			assert.True(t, odd(v))
+		}
+		for _, v := range bs[split:] {
+			assert.False(t, odd(v))
+		}
+
+		want := make([]byte, 0, len(stable))
+		for _, v := range stable {
+			if odd(v) {
+				want = append(want, v)
+			}
+		}
+		for _, v := range stable {
+			if !odd(v) {
+				want = append(want, v)
+			}
+		}
+
+		assert.Equal(t, split, StablePartition(stable, odd))
+		assert.Equal(t, want, stable)
+	})
+}
+
+func TestStablePartition(t *testing.T) {
+	x := []int{1, 2, 3, 4, 5, 6}
+	split := StablePartition(x, func(v int) bool {
+		return v%2 == 0
+	})
+	assert.Equal(t, 3, split)
+	assert.Equal(t, []int{2, 4, 6, 1, 3, 5}, x)
+
+	assert.Equal(t, 0, Partition(nil, func(int) bool { return true }))
+}
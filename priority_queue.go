@@ -12,19 +12,44 @@ type (
 	//  Limit: Max capacity. If 0, then unlimited.
 	//  Locker: Queue lock. If nil, then SpinLock.
 	//  Compare: Comparator function.
+	//  Arity: Heap arity, see DHeapPush. If 0, then 2.
 	PriorityQueueOptions[T any] struct {
 		Limit   uint
 		Locker  sync.Locker
 		Compare Compare[T]
+		Arity   uint
 	}
 
 	// The PriorityQueue is a thread-safe priority queue.
 	PriorityQueue[T any] struct {
-		heap       []T
-		compare    Compare[T]
-		lim        int
+		heap    []priorityQueueEntry[T]
+		compare Compare[priorityQueueEntry[T]]
+		arity   int
+		// Zero for unlimited.
+		lim int
+		// Wakes up one popper at a time, the popper passes the
+		// signal on if there's something left in the heap.
 		readyToPop chan struct{}
-		locker     sync.Locker
+		// Same for the pushers, signaled when there's room.
+		readyToPush chan struct{}
+		locker      sync.Locker
+		// Closed by Close.
+		closed chan struct{}
+		// Closed once the queue is both closed and empty.
+		drained chan struct{}
+	}
+
+	// PriorityQueueHandle refers to an element pushed onto the priority
+	// queue. See PriorityQueue.Update and PriorityQueue.Remove.
+	PriorityQueueHandle struct {
+		// Index within the heap, -1 once the element has left the queue.
+		index int
+	}
+
+	priorityQueueEntry[T any] struct {
+		value T
+		// Nil unless requested by the pusher.
+		handle *PriorityQueueHandle
 	}
 )
 
@@ -41,16 +66,27 @@ func NewPriorityQueue[T any](opts PriorityQueueOptions[T]) (*PriorityQueue[T], e
 	if opts.Compare == nil {
 		return nil, fmt.Errorf("%w: nil comparator", ErrBadOptions)
 	}
-	if opts.Limit == 0 {
-		return nil, fmt.Errorf("%w: unlimitied pq's are not supported", ErrBadOptions)
+	if opts.Arity == 0 {
+		opts.Arity = 2
+	}
+	if opts.Arity == 1 {
+		return nil, fmt.Errorf("%w: unary heaps are not supported", ErrBadOptions)
 	}
 
+	compare := opts.Compare
+	// Unlimited heap starts empty and grows on demand.
 	return &PriorityQueue[T]{
-		heap:       make([]T, 0, opts.Limit),
-		compare:    opts.Compare,
-		locker:     opts.Locker,
-		readyToPop: make(chan struct{}, opts.Limit),
-		lim:        int(opts.Limit),
+		heap: make([]priorityQueueEntry[T], 0, opts.Limit),
+		compare: func(a, b priorityQueueEntry[T]) int {
+			return compare(a.value, b.value)
+		},
+		locker:      opts.Locker,
+		readyToPop:  make(chan struct{}, 1),
+		readyToPush: make(chan struct{}, 1),
+		arity:       int(opts.Arity),
+		lim:         int(opts.Limit),
+		closed:      make(chan struct{}),
+		drained:     make(chan struct{}),
 	}, nil
 }
 
@@ -58,57 +94,518 @@ func NewPriorityQueue[T any](opts PriorityQueueOptions[T]) (*PriorityQueue[T], e
 //
 //	x: Element to push.
 //
-// Returns true if the element has been pushed or false is the queue is full.
-func (pq *PriorityQueue[T]) TryPush(x T) bool {
+// Returns ErrFull if the queue is full or ErrClosed if the queue is closed.
+// Unlimited queue is never full.
+func (pq *PriorityQueue[T]) TryPush(x T) error {
+	return pq.tryPush(x, nil)
+}
+
+// TryPushHandle attempts to push an element onto the priority queue.
+//
+//	x: Element to push.
+//
+// Returns the element handle, or nil and an error if the element
+// hasn't been pushed. See TryPush for the errors.
+func (pq *PriorityQueue[T]) TryPushHandle(x T) (*PriorityQueueHandle, error) {
+	handle := new(PriorityQueueHandle)
+	if err := pq.tryPush(x, handle); err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
+
+func (pq *PriorityQueue[T]) tryPush(x T, handle *PriorityQueueHandle) error {
 	pq.locker.Lock()
-	if len(pq.heap)+1 > pq.lim {
-		pq.locker.Unlock()
-		return false
+	defer pq.locker.Unlock()
+
+	if pq.isClosedLF() {
+		return ErrClosed
+	}
+	if pq.lim != 0 && len(pq.heap)+1 > pq.lim {
+		return ErrFull
 	}
 
-	HeapPush(&pq.heap, x, pq.compare)
-	select {
-	case pq.readyToPop <- struct{}{}:
-	default:
+	pq.pushLF(x, handle)
+	pq.notifyPopLF()
+	pq.notifyPushLF()
+	return nil
+}
+
+// PushMany attempts to push the elements onto the priority queue at once.
+//
+//	xs: Elements to push.
+//
+// Returns the number of elements pushed. If some of the elements
+// haven't been pushed, returns ErrFull if the queue is full or
+// ErrClosed if the queue is closed. Unlimited queue is never full.
+func (pq *PriorityQueue[T]) PushMany(xs []T) (int, error) {
+	pq.locker.Lock()
+	defer pq.locker.Unlock()
+
+	if pq.isClosedLF() {
+		return 0, ErrClosed
 	}
-	pq.locker.Unlock()
-	return true
+
+	var err error
+	n := len(xs)
+	if pq.lim != 0 && pq.lim-len(pq.heap) < n {
+		n = pq.lim - len(pq.heap)
+		err = ErrFull
+	}
+
+	// Rebuilding the heap is cheaper than sifting up every element
+	// when it's the batch that makes the most of the heap.
+	if n >= len(pq.heap) {
+		for _, x := range xs[:n] {
+			pq.heap = append(pq.heap, priorityQueueEntry[T]{value: x})
+		}
+		dheapify(pq.heap, pq.compare, priorityQueueSwap[T], pq.arity)
+	} else {
+		for _, x := range xs[:n] {
+			pq.pushLF(x, nil)
+		}
+	}
+
+	pq.notifyPopLF()
+	pq.notifyPushLF()
+	return n, err
 }
 
-// Pop the highest priority element from the priority queue.
+// Push an element onto the priority queue.
 //
 //	ctx: Cancellation context. If nil, defaults to context.Background().
+//	x: Element to push.
 //
-// Blocks indefinitely until there's either something to pop
+// Blocks indefinitely until there's either room for the element
 // or the context is done.
 //
-// Returns the default value and false in case the context is done.
-func (pq *PriorityQueue[T]) Pop(ctx context.Context) (T, bool) {
+// Returns the context error in case the context is done,
+// or ErrClosed if the queue is closed.
+func (pq *PriorityQueue[T]) Push(ctx context.Context, x T) error {
+	return pq.push(ctx, x, nil)
+}
+
+// PushHandle pushes an element onto the priority queue.
+//
+// This is a handle returning version of Push.
+//
+// See Push for more details.
+func (pq *PriorityQueue[T]) PushHandle(ctx context.Context, x T) (*PriorityQueueHandle, error) {
+	handle := new(PriorityQueueHandle)
+	if err := pq.push(ctx, x, handle); err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
+
+func (pq *PriorityQueue[T]) push(ctx context.Context, x T, handle *PriorityQueueHandle) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	for {
+		err := pq.tryPush(x, handle)
+		if err != ErrFull {
+			return err
+		}
+
+		select {
+		case <-pq.readyToPush:
+		case <-pq.closed:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Pop the highest priority element from the priority queue.
+//
+//	ctx: Cancellation context. If nil, defaults to context.Background().
+//
+// Blocks indefinitely until there's either something to pop,
+// the queue is closed or the context is done. The closed queue
+// is popped until it's empty.
+//
+// Returns the default value and false in case the context is done
+// or the queue is closed and empty.
+func (pq *PriorityQueue[T]) Pop(ctx context.Context) (T, bool) {
+	var buf [1]T
+	xs, ok := pq.popN(ctx, buf[:0], 1)
+	if !ok {
+		var z T
+		return z, false
+	}
+	return xs[0], true
+}
+
+// PopN pops up to n highest priority elements from the priority queue.
+//
+//	ctx: Cancellation context. If nil, defaults to context.Background().
+//	n: Max number of elements to pop.
+//
+// Blocks the same way Pop does.
+//
+// Returns the elements in priority order, or nil and false
+// in case the context is done or the queue is closed and empty.
+// If n isn't positive, returns nil and true right away.
+func (pq *PriorityQueue[T]) PopN(ctx context.Context, n int) ([]T, bool) {
+	if n <= 0 {
+		return nil, true
+	}
+	return pq.popN(ctx, nil, n)
+}
+
+// Drain pops all the elements from the priority queue at once.
+//
+// Returns the elements in priority order.
+func (pq *PriorityQueue[T]) Drain() []T {
 	pq.locker.Lock()
-	if len(pq.heap) != 0 {
-		x := HeapPop(&pq.heap, pq.compare)
-		pq.locker.Unlock()
-		return x, true
+	defer pq.locker.Unlock()
+
+	return pq.popLF(make([]T, 0, len(pq.heap)), len(pq.heap))
+}
+
+func (pq *PriorityQueue[T]) popN(ctx context.Context, dst []T, n int) ([]T, bool) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	pq.locker.Unlock()
 
-	var z T
-	select {
-	case <-pq.readyToPop:
+	for {
 		pq.locker.Lock()
-		// Another goroutine could've popped the heap already.
-		if len(pq.heap) == 0 {
+		if len(pq.heap) != 0 {
+			dst = pq.popLF(dst, n)
 			pq.locker.Unlock()
-			return z, false
+			return dst, true
 		}
-		x := HeapPop(&pq.heap, pq.compare)
 		pq.locker.Unlock()
-		return x, true
-	case <-ctx.Done():
+
+		select {
+		case <-pq.readyToPop:
+			// Another goroutine could've popped the heap already,
+			// retrying until either there's something left or done.
+		case <-pq.drained:
+			return dst, false
+		case <-ctx.Done():
+			return dst, false
+		}
+	}
+}
+
+// RelayFrom pushes the values read from the source channel onto the queue.
+//
+//	ctx: Cancellation context. If nil, defaults to context.Background().
+//	source: Channel to read from.
+//
+// Blocks until the source is closed, the queue is closed or
+// the context is done, waiting for room if the queue is full.
+func (pq *PriorityQueue[T]) RelayFrom(ctx context.Context, source <-chan T) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		select {
+		case value, more := <-source:
+			if !more {
+				return
+			}
+
+			if pq.Push(ctx, value) != nil {
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RelayTo pops the values from the queue to the sink channel,
+// highest priority first.
+//
+//	ctx: Cancellation context. If nil, defaults to context.Background().
+//	sink: Channel to write to.
+//
+// Blocks until the context is done or the queue is closed and empty.
+// The value popped while the context was done is pushed back.
+//
+// Returns the undelivered value and true if it couldn't be pushed
+// back, as the queue has been closed or refilled meanwhile.
+func (pq *PriorityQueue[T]) RelayTo(ctx context.Context, sink chan<- T) (T, bool) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var z T
+	// Pop doesn't check the context if there's something to pop.
+	for ctx.Err() == nil {
+		value, ok := pq.Pop(ctx)
+		if !ok {
+			return z, false
+		}
+
+		select {
+		case sink <- value:
+		case <-ctx.Done():
+			if err := pq.TryPush(value); err != nil {
+				return value, true
+			}
+			return z, false
+		}
+	}
+	return z, false
+}
+
+// Len returns the number of elements in the priority queue.
+func (pq *PriorityQueue[T]) Len() int {
+	pq.locker.Lock()
+	defer pq.locker.Unlock()
+	return len(pq.heap)
+}
+
+// Cap returns the priority queue limit, 0 if unlimited.
+func (pq *PriorityQueue[T]) Cap() int {
+	return pq.lim
+}
+
+// Peek returns the highest priority element without popping it.
+//
+// Returns the default value and false if the queue is empty.
+func (pq *PriorityQueue[T]) Peek() (T, bool) {
+	pq.locker.Lock()
+	defer pq.locker.Unlock()
+
+	if len(pq.heap) == 0 {
+		var z T
 		return z, false
 	}
+	return pq.heap[0].value, true
+}
+
+// Snapshot copies the priority queue elements.
+//
+// Returns the elements in priority order.
+func (pq *PriorityQueue[T]) Snapshot() []T {
+	pq.locker.Lock()
+	entries := append([]priorityQueueEntry[T](nil), pq.heap...)
+	pq.locker.Unlock()
+
+	// Sorting the copy outside the lock.
+	SortHeap(entries, CompareReverse(pq.compare))
+	values := make([]T, len(entries))
+	for i := range entries {
+		values[i] = entries[i].value
+	}
+	return values
+}
+
+// Close closes the priority queue.
+//
+// The pushes fail with ErrClosed afterwards, while the pops proceed
+// until the queue is empty. Blocked pushers and poppers are released.
+//
+// Closing the closed queue is no-op.
+func (pq *PriorityQueue[T]) Close() {
+	pq.locker.Lock()
+	defer pq.locker.Unlock()
+
+	if pq.isClosedLF() {
+		return
+	}
+	close(pq.closed)
+	pq.checkDrainedLF()
+}
+
+// WaitContext blocks until the priority queue is closed and empty.
+//
+//	wait: Cancellation context. If nil, defaults to context.Background().
+//
+// Returns an error if the wait was canceled.
+func (pq *PriorityQueue[T]) WaitContext(wait context.Context) error {
+	if wait == nil {
+		wait = context.Background()
+	}
+
+	select {
+	case <-wait.Done():
+		return wait.Err()
+	case <-pq.drained:
+		return nil
+	}
+}
+
+// Wait blocks until the priority queue is closed and empty.
+//
+// This is a convenience function for WaitContext with background wait context.
+//
+// See WaitContext for more details.
+func (pq *PriorityQueue[T]) Wait() {
+	_ = pq.WaitContext(context.Background())
+}
+
+// Update replaces the element referred by the handle and restores
+// the queue order.
+//
+//	handle: Handle returned by one of the push methods.
+//	x: New element value.
+//
+// Returns false if the element has already left the queue.
+func (pq *PriorityQueue[T]) Update(handle *PriorityQueueHandle, x T) bool {
+	pq.locker.Lock()
+	defer pq.locker.Unlock()
+
+	if !pq.ownsLF(handle) {
+		return false
+	}
+
+	pq.heap[handle.index].value = x
+	pq.fixLF(handle.index)
+	return true
+}
+
+// Remove removes the element referred by the handle from the queue.
+//
+//	handle: Handle returned by one of the push methods.
+//
+// Returns the removed element, or the default value and false
+// if the element has already left the queue.
+func (pq *PriorityQueue[T]) Remove(handle *PriorityQueueHandle) (T, bool) {
+	pq.locker.Lock()
+	defer pq.locker.Unlock()
+
+	if !pq.ownsLF(handle) {
+		var z T
+		return z, false
+	}
+
+	x := pq.removeLF(handle.index)
+	pq.notifyPushLF()
+	pq.checkDrainedLF()
+	return x, true
+}
+
+// pushLF pushes the element onto the heap.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) pushLF(x T, handle *PriorityQueueHandle) {
+	i := len(pq.heap)
+	if handle != nil {
+		handle.index = i
+	}
+
+	pq.heap = append(pq.heap, priorityQueueEntry[T]{value: x, handle: handle})
+	dheapSiftUp(pq.heap, pq.compare, priorityQueueSwap[T], pq.arity, i)
+}
+
+// popLF pops up to n elements from the heap, appending them to dst.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) popLF(dst []T, n int) []T {
+	for ; n > 0 && len(pq.heap) != 0; n-- {
+		dst = append(dst, pq.removeLF(0))
+	}
+
+	pq.notifyPopLF()
+	pq.notifyPushLF()
+	pq.checkDrainedLF()
+	return dst
+}
+
+// removeLF removes the i-th element from the heap.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) removeLF(i int) T {
+	last := len(pq.heap) - 1
+	priorityQueueSwap(pq.heap, i, last)
+
+	entry := pq.heap[last]
+	if entry.handle != nil {
+		entry.handle.index = -1
+	}
+	// Not retaining the removed value.
+	pq.heap[last] = priorityQueueEntry[T]{}
+	pq.heap = pq.heap[:last]
+
+	if i != last {
+		pq.fixLF(i)
+	}
+	return entry.value
+}
+
+// fixLF restores the heap order after the i-th element has changed.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) fixLF(i int) {
+	if i > 0 && pq.compare.Greater(pq.heap[i], pq.heap[(i-1)/pq.arity]) {
+		dheapSiftUp(pq.heap, pq.compare, priorityQueueSwap[T], pq.arity, i)
+		return
+	}
+	dheapSiftDown(pq.heap, pq.compare, priorityQueueSwap[T], pq.arity, i)
+}
+
+// ownsLF reports whether the handle refers to an element of this queue.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) ownsLF(handle *PriorityQueueHandle) bool {
+	return handle != nil &&
+		handle.index >= 0 &&
+		handle.index < len(pq.heap) &&
+		pq.heap[handle.index].handle == handle
+}
+
+// isClosedLF reports whether the queue is closed.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) isClosedLF() bool {
+	select {
+	case <-pq.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkDrainedLF signals the closed queue has become empty.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) checkDrainedLF() {
+	if len(pq.heap) != 0 || !pq.isClosedLF() {
+		return
+	}
+
+	select {
+	case <-pq.drained:
+	default:
+		close(pq.drained)
+	}
+}
+
+// notifyPopLF wakes up a popper if the heap isn't empty.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) notifyPopLF() {
+	if len(pq.heap) == 0 {
+		return
+	}
+
+	select {
+	case pq.readyToPop <- struct{}{}:
+	default:
+	}
+}
+
+// notifyPushLF wakes up a pusher if the heap isn't full.
+// Must be called with the lock held.
+func (pq *PriorityQueue[T]) notifyPushLF() {
+	if pq.lim != 0 && len(pq.heap) >= pq.lim {
+		return
+	}
+
+	select {
+	case pq.readyToPush <- struct{}{}:
+	default:
+	}
+}
+
+func priorityQueueSwap[T any](heap []priorityQueueEntry[T], i, j int) {
+	heap[i], heap[j] = heap[j], heap[i]
+	if heap[i].handle != nil {
+		heap[i].handle.index = i
+	}
+	if heap[j].handle != nil {
+		heap[j].handle.index = j
+	}
 }
@@ -2,9 +2,12 @@ package sly
 
 import (
 	"context"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -29,14 +32,24 @@ func TestNewPriorityQueue(t *testing.T) {
 		assert.ErrorIs(t, err, ErrBadOptions)
 	})
 
-	t.Run("no limit", func(t *testing.T) {
+	t.Run("unary", func(t *testing.T) {
 		opts := PriorityQueueOptions[int]{
 			Compare: CompareOrdered[int],
+			Arity:   1,
 		}
 		pq, err := NewPriorityQueue(opts)
 		assert.Nil(t, pq)
 		assert.ErrorIs(t, err, ErrBadOptions)
 	})
+
+	t.Run("no limit", func(t *testing.T) {
+		opts := PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		}
+		pq, err := NewPriorityQueue(opts)
+		assert.NotNil(t, pq)
+		assert.NoError(t, err)
+	})
 }
 
 func TestPriorityQueue(t *testing.T) {
@@ -48,10 +61,10 @@ func TestPriorityQueue(t *testing.T) {
 		assert.NotNil(t, pq)
 		assert.NoError(t, err)
 
-		assert.True(t, pq.TryPush(1))
-		assert.True(t, pq.TryPush(2))
-		assert.True(t, pq.TryPush(3))
-		assert.False(t, pq.TryPush(4))
+		assert.NoError(t, pq.TryPush(1))
+		assert.NoError(t, pq.TryPush(2))
+		assert.NoError(t, pq.TryPush(3))
+		assert.ErrorIs(t, pq.TryPush(4), ErrFull)
 
 		v, ok := pq.Pop(context.Background())
 		assert.Equal(t, 3, v)
@@ -92,6 +105,82 @@ func TestPriorityQueue(t *testing.T) {
 		wg.Wait()
 	})
 
+	t.Run("unlimited", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		for i := 0; i < 10000; i++ {
+			assert.NoError(t, pq.TryPush(i))
+		}
+		for i := 9999; i >= 0; i-- {
+			v, ok := pq.Pop(nil)
+			assert.Equal(t, i, v)
+			assert.True(t, ok)
+		}
+	})
+
+	t.Run("pop wake many", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		const n = 8
+		wg := sync.WaitGroup{}
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer wg.Done()
+				_, _ = pq.Pop(nil)
+			}()
+		}
+
+		// Giving poppers some time to block.
+		time.Sleep(10 * time.Millisecond)
+		for i := 0; i < n; i++ {
+			pq.TryPush(i)
+		}
+		wg.Wait()
+	})
+
+	t.Run("push block", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Limit:   1,
+			Compare: CompareOrdered[int],
+		})
+		assert.NoError(t, pq.Push(nil, 1))
+
+		pushed := make(chan struct{})
+		go func() {
+			assert.NoError(t, pq.Push(nil, 2))
+			close(pushed)
+		}()
+
+		v, ok := pq.Pop(nil)
+		assert.Equal(t, 1, v)
+		assert.True(t, ok)
+
+		<-pushed
+		v, ok = pq.Pop(nil)
+		assert.Equal(t, 2, v)
+		assert.True(t, ok)
+	})
+
+	t.Run("push unblock on cancel", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Limit:   1,
+			Compare: CompareOrdered[int],
+		})
+		assert.NoError(t, pq.TryPush(1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-time.After(time.Millisecond)
+			cancel()
+		}()
+		assert.Equal(t, context.Canceled, pq.Push(ctx, 2))
+	})
+
 	t.Run("pop unblock on close", func(t *testing.T) {
 		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
 			Limit:   3,
@@ -111,6 +200,526 @@ func TestPriorityQueue(t *testing.T) {
 	})
 }
 
+func TestPriorityQueueHandle(t *testing.T) {
+	t.Run("update", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		h1, err := pq.TryPushHandle(1)
+		assert.NoError(t, err)
+		h2, err := pq.PushHandle(nil, 2)
+		assert.NoError(t, err)
+		pq.TryPush(3)
+
+		assert.True(t, pq.Update(h1, 4))
+		assert.True(t, pq.Update(h2, 0))
+
+		for _, want := range []int{4, 3, 0} {
+			v, _ := pq.Pop(nil)
+			assert.Equal(t, want, v)
+		}
+		assert.False(t, pq.Update(h1, 1))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Limit:   3,
+			Compare: CompareOrdered[int],
+		})
+
+		pq.TryPush(1)
+		h, _ := pq.TryPushHandle(2)
+		pq.TryPush(3)
+		_, err := pq.TryPushHandle(4)
+		assert.ErrorIs(t, err, ErrFull)
+
+		v, ok := pq.Remove(h)
+		assert.Equal(t, 2, v)
+		assert.True(t, ok)
+		_, ok = pq.Remove(h)
+		assert.False(t, ok)
+
+		// Room has been freed.
+		assert.NoError(t, pq.TryPush(0))
+		for _, want := range []int{3, 1, 0} {
+			v, _ := pq.Pop(nil)
+			assert.Equal(t, want, v)
+		}
+	})
+
+	t.Run("foreign handle", func(t *testing.T) {
+		pq1, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+		pq2, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		h, _ := pq1.TryPushHandle(1)
+		pq2.TryPush(2)
+		assert.False(t, pq2.Update(h, 3))
+		_, ok := pq2.Remove(h)
+		assert.False(t, ok)
+		assert.False(t, pq2.Update(nil, 3))
+	})
+}
+
+func FuzzPriorityQueueHandle(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49}, uint8(0))
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49}, uint8(2))
+	f.Add([]byte(nil), uint8(0))
+	f.Fuzz(func(t *testing.T, bs []byte, d uint8) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+			Arity:   uint(d%8) + 2,
+		})
+
+		handles := make([]*PriorityQueueHandle, 0, len(bs))
+		want := make(map[*PriorityQueueHandle]int, len(bs))
+		for _, b := range bs {
+			h, _ := pq.TryPushHandle(int(b))
+			handles = append(handles, h)
+			want[h] = int(b)
+		}
+
+		// Shuffling priorities of the even ones, removing every third.
+		for i, h := range handles {
+			switch {
+			case i%3 == 0:
+				_, _ = pq.Remove(h)
+				delete(want, h)
+			case i%2 == 0:
+				pq.Update(h, int(bs[len(bs)-1-i]))
+				want[h] = int(bs[len(bs)-1-i])
+			}
+		}
+
+		values := make([]int, 0, len(want))
+		for _, v := range want {
+			values = append(values, v)
+		}
+		SortHeap(values, CompareReverse(CompareOrdered[int]))
+
+		for _, v := range values {
+			have, _ := pq.Pop(nil)
+			if have != v {
+				t.Fatalf("want: %v, have: %v", v, have)
+			}
+		}
+	})
+}
+
+func TestPriorityQueueBatch(t *testing.T) {
+	t.Run("push many", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Limit:   5,
+			Compare: CompareOrdered[int],
+		})
+
+		n, err := pq.PushMany([]int{3, 1, 2})
+		assert.Equal(t, 3, n)
+		assert.NoError(t, err)
+
+		// Sifting up into the bigger heap, full afterwards.
+		n, err = pq.PushMany([]int{0, 4, 5})
+		assert.Equal(t, 2, n)
+		assert.ErrorIs(t, err, ErrFull)
+
+		n, err = pq.PushMany([]int{6})
+		assert.Equal(t, 0, n)
+		assert.ErrorIs(t, err, ErrFull)
+
+		assert.Equal(t, []int{4, 3, 2, 1, 0}, pq.Drain())
+	})
+
+	t.Run("push many handles", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		h, _ := pq.TryPushHandle(1)
+		// Heapifying must keep the handles valid.
+		pq.PushMany([]int{5, 4, 3, 2})
+		assert.True(t, pq.Update(h, 6))
+		assert.Equal(t, []int{6, 5, 4, 3, 2}, pq.Drain())
+	})
+
+	t.Run("pop n", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+		pq.PushMany([]int{1, 4, 2, 3})
+
+		xs, ok := pq.PopN(nil, 3)
+		assert.Equal(t, []int{4, 3, 2}, xs)
+		assert.True(t, ok)
+
+		xs, ok = pq.PopN(nil, 3)
+		assert.Equal(t, []int{1}, xs)
+		assert.True(t, ok)
+	})
+
+	t.Run("pop n non-positive", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		// Must not block on the empty queue.
+		xs, ok := pq.PopN(nil, 0)
+		assert.Nil(t, xs)
+		assert.True(t, ok)
+
+		pq.PushMany([]int{1, 2})
+		xs, ok = pq.PopN(nil, -1)
+		assert.Nil(t, xs)
+		assert.True(t, ok)
+		assert.Equal(t, 2, pq.Len())
+	})
+
+	t.Run("pop n block", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		popped := make(chan []int)
+		go func() {
+			xs, _ := pq.PopN(nil, 2)
+			popped <- xs
+		}()
+
+		// Giving the popper time to block.
+		time.Sleep(10 * time.Millisecond)
+		pq.PushMany([]int{1, 2})
+		assert.Equal(t, []int{2, 1}, <-popped)
+	})
+
+	t.Run("pop n cancel", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		xs, ok := pq.PopN(ctx, 2)
+		assert.Nil(t, xs)
+		assert.False(t, ok)
+	})
+
+	t.Run("drain wakes pushers", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Limit:   1,
+			Compare: CompareOrdered[int],
+		})
+		pq.TryPush(1)
+
+		pushed := make(chan struct{})
+		go func() {
+			assert.NoError(t, pq.Push(nil, 2))
+			close(pushed)
+		}()
+
+		// Giving the pusher time to block.
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, []int{1}, pq.Drain())
+		<-pushed
+		assert.Equal(t, []int{2}, pq.Drain())
+		assert.Empty(t, pq.Drain())
+	})
+}
+
+func TestPriorityQueueContention(t *testing.T) {
+	pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+		Limit:   4,
+		Compare: CompareOrdered[int],
+	})
+
+	const (
+		npushers = 8
+		npoppers = 32
+		npops    = 1000
+	)
+
+	// Releasing the pushers in case the poppers give up early.
+	ctx, cancel := context.WithCancel(context.Background())
+	pushers := sync.WaitGroup{}
+	pushers.Add(npushers)
+	for i := 0; i < npushers; i++ {
+		go func() {
+			defer pushers.Done()
+			for j := 0; j < npoppers*npops/npushers; j++ {
+				if pq.Push(ctx, j) != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	var spurious int32
+	poppers := sync.WaitGroup{}
+	poppers.Add(npoppers)
+	for i := 0; i < npoppers; i++ {
+		go func() {
+			defer poppers.Done()
+			for j := 0; j < npops; j++ {
+				// The context is never done, Pop must not fail.
+				if _, ok := pq.Pop(nil); !ok {
+					atomic.AddInt32(&spurious, 1)
+				}
+			}
+		}()
+	}
+
+	poppers.Wait()
+	cancel()
+	pushers.Wait()
+	assert.Zero(t, spurious)
+}
+
+func TestPriorityQueueIntrospection(t *testing.T) {
+	pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+		Limit:   4,
+		Compare: CompareOrdered[int],
+	})
+	assert.Equal(t, 0, pq.Len())
+	assert.Equal(t, 4, pq.Cap())
+	assert.Empty(t, pq.Snapshot())
+
+	_, ok := pq.Peek()
+	assert.False(t, ok)
+
+	_, _ = pq.PushMany([]int{2, 3, 1})
+	assert.Equal(t, 3, pq.Len())
+
+	v, ok := pq.Peek()
+	assert.Equal(t, 3, v)
+	assert.True(t, ok)
+
+	assert.Equal(t, []int{3, 2, 1}, pq.Snapshot())
+	// Left intact.
+	assert.Equal(t, 3, pq.Len())
+	assert.Equal(t, []int{3, 2, 1}, pq.Drain())
+
+	unlimited, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+		Compare: CompareOrdered[int],
+	})
+	assert.Equal(t, 0, unlimited.Cap())
+}
+
+func TestPriorityQueueClose(t *testing.T) {
+	t.Run("pop until empty", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+		_, _ = pq.PushMany([]int{1, 2})
+		pq.Close()
+		// Must not panic.
+		pq.Close()
+
+		assert.ErrorIs(t, pq.TryPush(3), ErrClosed)
+		assert.ErrorIs(t, pq.Push(nil, 3), ErrClosed)
+		_, err := pq.PushMany([]int{3})
+		assert.ErrorIs(t, err, ErrClosed)
+
+		v, ok := pq.Pop(nil)
+		assert.Equal(t, 2, v)
+		assert.True(t, ok)
+		v, ok = pq.Pop(nil)
+		assert.Equal(t, 1, v)
+		assert.True(t, ok)
+
+		_, ok = pq.Pop(nil)
+		assert.False(t, ok)
+		pq.Wait()
+		assert.NoError(t, pq.WaitContext(nil))
+	})
+
+	t.Run("release poppers", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		popped := make(chan bool)
+		go func() {
+			_, ok := pq.PopN(nil, 1)
+			popped <- ok
+		}()
+
+		// Giving the popper time to block.
+		time.Sleep(10 * time.Millisecond)
+		pq.Close()
+		assert.False(t, <-popped)
+	})
+
+	t.Run("release pushers", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Limit:   1,
+			Compare: CompareOrdered[int],
+		})
+		assert.NoError(t, pq.TryPush(1))
+
+		pushed := make(chan error)
+		go func() {
+			pushed <- pq.Push(nil, 2)
+		}()
+
+		// Giving the pusher time to block.
+		time.Sleep(10 * time.Millisecond)
+		pq.Close()
+		assert.ErrorIs(t, <-pushed, ErrClosed)
+	})
+
+	t.Run("relay", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+		pq.RelayFrom(nil, ChanStream(nil, 0, 1, 2))
+		pq.Close()
+
+		sink := make(chan int, 2)
+		pq.RelayTo(nil, sink)
+		assert.Equal(t, 2, <-sink)
+		assert.Equal(t, 1, <-sink)
+	})
+
+	t.Run("wait cancel", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+		_ = pq.TryPush(1)
+		pq.Close()
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+
+		// Closed, but not empty yet.
+		assert.Equal(t, context.Canceled, pq.WaitContext(ctx))
+	})
+}
+
+func TestPriorityQueueRelay(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		pq.RelayFrom(nil, ChanStream(nil, 0, 2, 1, 3))
+
+		sink := make(chan int)
+		go pq.RelayTo(nil, sink)
+		assert.Equal(t, 3, <-sink)
+		assert.Equal(t, 2, <-sink)
+		assert.Equal(t, 1, <-sink)
+	})
+
+	t.Run("relay from full", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Limit:   1,
+			Compare: CompareOrdered[int],
+		})
+
+		relayed := make(chan struct{})
+		go func() {
+			pq.RelayFrom(nil, ChanStream(nil, 0, 1, 2))
+			close(relayed)
+		}()
+
+		v, _ := pq.Pop(nil)
+		assert.Equal(t, 1, v)
+		<-relayed
+		v, _ = pq.Pop(nil)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run("relay from cancel", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Limit:   1,
+			Compare: CompareOrdered[int],
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-time.After(time.Millisecond)
+			cancel()
+		}()
+		// Blocks on the full queue until canceled.
+		pq.RelayFrom(ctx, ChanStream(nil, 0, 1, 2))
+	})
+
+	t.Run("relay to cancel", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+		pq.TryPush(1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-time.After(time.Millisecond)
+			cancel()
+		}()
+		// Blocks on the unread sink until canceled.
+		_, lost := pq.RelayTo(ctx, make(chan int))
+		assert.False(t, lost)
+
+		// The undelivered value is back.
+		v, ok := pq.Pop(nil)
+		assert.Equal(t, 1, v)
+		assert.True(t, ok)
+	})
+
+	t.Run("relay to cancel closed", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+		pq.TryPush(1)
+		pq.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			// Canceling once the value is popped.
+			for pq.Len() != 0 {
+				runtime.Gosched()
+			}
+			cancel()
+		}()
+
+		// Can't be pushed back into the closed queue, returned instead.
+		v, lost := pq.RelayTo(ctx, make(chan int))
+		assert.Equal(t, 1, v)
+		assert.True(t, lost)
+	})
+
+	t.Run("relay to canceled", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+		pq.TryPush(1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Nothing is popped once the context is done.
+		_, lost := pq.RelayTo(ctx, make(chan int, 1))
+		assert.False(t, lost)
+		assert.Equal(t, 1, pq.Len())
+	})
+
+	t.Run("merge pipeline", func(t *testing.T) {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+		})
+
+		pq.RelayFrom(nil, ChanMerge(nil, 0, ChanStream(nil, 0, 1, 5), ChanStream(nil, 0, 4, 2)))
+
+		sink := make(chan int)
+		go pq.RelayTo(nil, sink)
+		for _, want := range []int{5, 4, 2, 1} {
+			assert.Equal(t, want, <-sink)
+		}
+	})
+}
+
 func BenchmarkPriorityQueue(b *testing.B) {
 	pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
 		Limit:   4096 * 32,
@@ -119,8 +728,31 @@ func BenchmarkPriorityQueue(b *testing.B) {
 
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			pq.TryPush(rand.Int())
+			_ = pq.TryPush(rand.Int())
 			_, _ = pq.Pop(context.Background())
 		}
 	})
 }
+
+func BenchmarkPriorityQueueArity(b *testing.B) {
+	const n = 1 << 16
+	values := make([]int, n)
+	for i := range values {
+		values[i] = rand.Int()
+	}
+
+	for _, d := range []uint{2, 4, 8} {
+		pq, _ := NewPriorityQueue(PriorityQueueOptions[int]{
+			Compare: CompareOrdered[int],
+			Arity:   d,
+		})
+		b.Run(fmt.Sprintf("d=%d", d), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = pq.PushMany(values)
+				for pq.Len() > 0 {
+					_, _ = pq.Pop(nil)
+				}
+			}
+		})
+	}
+}
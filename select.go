@@ -0,0 +1,102 @@
+package sly
+
+// Total length the quickselect is allowed to partition, in the
+// input lengths, before falling back to the median of medians. The
+// good pivots keep it about 2, so only the adversarial inputs hit it.
+const selectWorkFactor = 4
+
+// NthElement is an in-place quickselect.
+//
+//	x: Slice to select from.
+//	k: Index to select, 0 <= k < len(x).
+//	compare: Comparator function.
+//
+// Reorders x so that x[k] is the value it would have been if x
+// were sorted, with all(y <= x[k] for y in x[:k]) and
+// all(y >= x[k] for y in x[k+1:]).
+//
+// Runs in expected O(n), falling back to the median of medians pivot
+// once the partitioning work exceeds a multiple of len(x), which makes
+// it O(n) in the worst case.
+func NthElement[T any](x []T, k int, compare Compare[T]) {
+	selectNth(x, k, compare, selectWorkFactor*len(x))
+}
+
+// PartialSort is an in-place partial sort.
+//
+//	x: Slice to sort.
+//	k: Number of values to sort.
+//	compare: Comparator function.
+//
+// Reorders x so that x[:k] are the k smallest values in sorted order.
+// The order of the rest is unspecified.
+func PartialSort[T any](x []T, k int, compare Compare[T]) {
+	if k <= 0 {
+		return
+	}
+	if k < len(x) {
+		NthElement(x, k, compare)
+	} else {
+		k = len(x)
+	}
+	SortPdq(x[:k], compare)
+}
+
+// Median selects the median value in-place, see NthElement.
+//
+//	x: Non-empty slice to select from.
+//	compare: Comparator function.
+//
+// Returns the lower median for the slices of even length.
+func Median[T any](x []T, compare Compare[T]) T {
+	k := (len(x) - 1) >> 1
+	NthElement(x, k, compare)
+	return x[k]
+}
+
+// selectNth uses the median of medians pivot once the total
+// partitioned length exceeds the budget.
+func selectNth[T any](x []T, k int, compare Compare[T], budget int) {
+	for len(x) > sortInsertionThreshold {
+		var pivot T
+		if budget <= 0 {
+			pivot = selectMedianOfMedians(x, compare)
+		} else {
+			pivot = sortPivot(x, compare)
+		}
+
+		less, greater := PartitionFat(x, pivot, compare)
+		budget -= len(x)
+
+		switch {
+		case k < less:
+			x = x[:less]
+		case k > greater:
+			x = x[greater+1:]
+			k -= greater + 1
+		default:
+			return
+		}
+	}
+	sortInsertion(x, compare)
+}
+
+// selectMedianOfMedians picks the pivot guaranteed to have
+// at least 30% of x on both sides.
+func selectMedianOfMedians[T any](x []T, compare Compare[T]) T {
+	// Moving the medians of 5 to the front.
+	n := 0
+	for lo := 0; lo < len(x); lo += 5 {
+		hi := lo + 5
+		if hi > len(x) {
+			hi = len(x)
+		}
+		sortInsertion(x[lo:hi], compare)
+		SliceSwap(x, n, lo+(hi-lo)>>1)
+		n++
+	}
+
+	medians := x[:n]
+	selectNth(medians, n>>1, compare, 0)
+	return medians[n>>1]
+}
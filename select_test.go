@@ -0,0 +1,96 @@
+package sly
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func FuzzNthElement(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49, 20, 7, 90, 1}, uint8(5))
+	f.Add([]byte{1}, uint8(0))
+	f.Fuzz(func(t *testing.T, bs []byte, k uint8) {
+		if len(bs) == 0 {
+			return
+		}
+		nth := int(k) % len(bs)
+
+		h := append([]byte(nil), bs...)
+		sort.Slice(h, func(i, j int) bool {
+			return h[i] < h[j]
+		})
+
+		NthElement(bs, nth, CompareOrdered[byte])
+		if bs[nth] != h[nth] {
+			t.Fatalf("want: %v at %v, have: %v", h[nth], nth, bs[nth])
+		}
+		for _, v := range bs[:nth] {
+			assert.LessOrEqual(t, v, bs[nth])
+		}
+		for _, v := range bs[nth+1:] {
+			assert.GreaterOrEqual(t, v, bs[nth])
+		}
+	})
+}
+
+func TestNthElementMedianOfMedians(t *testing.T) {
+	x := make([]int, 1000)
+	for i := range x {
+		x[i] = len(x) - i
+	}
+
+	// No quickselect budget left, the median of medians all the way.
+	selectNth(x, 10, CompareOrdered[int], 0)
+	assert.Equal(t, 11, x[10])
+}
+
+func TestNthElementLinear(t *testing.T) {
+	const n = 1 << 14
+	patterns := map[string]func(i int) int{
+		"sorted":   func(i int) int { return i },
+		"reversed": func(i int) int { return n - i },
+		"organ pipe": func(i int) int {
+			if i < n/2 {
+				return i
+			}
+			return n - i
+		},
+		"sawtooth":    func(i int) int { return i % 64 },
+		"all equal":   func(int) int { return 0 },
+		"interleaved": func(i int) int { return i&1*n + i },
+	}
+	for name, pattern := range patterns {
+		t.Run(name, func(t *testing.T) {
+			x := make([]int, n)
+			for i := range x {
+				x[i] = pattern(i)
+			}
+
+			comparisons := 0
+			NthElement(x, n/3, func(a, b int) int {
+				comparisons++
+				return CompareOrdered(a, b)
+			})
+			assert.Less(t, comparisons, 40*n)
+		})
+	}
+}
+
+func TestPartialSort(t *testing.T) {
+	x := []int{9, 3, 7, 1, 8, 2, 6, 4, 5, 0, 15, 11, 13, 12, 14, 10}
+	PartialSort(x, 4, CompareOrdered[int])
+	assert.Equal(t, []int{0, 1, 2, 3}, x[:4])
+
+	PartialSort(x, 100, CompareOrdered[int])
+	assert.True(t, sort.IntsAreSorted(x))
+
+	// Must not panic.
+	PartialSort(x, 0, CompareOrdered[int])
+	PartialSort([]int(nil), 1, CompareOrdered[int])
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, 3, Median([]int{5, 1, 3, 4, 2}, CompareOrdered[int]))
+	assert.Equal(t, 2, Median([]int{4, 1, 3, 2}, CompareOrdered[int]))
+	assert.Equal(t, 1, Median([]int{1}, CompareOrdered[int]))
+}
@@ -0,0 +1,168 @@
+package sly
+
+import "math/bits"
+
+// Slices this short are insertion sorted.
+const sortInsertionThreshold = 12
+
+// SortPdq is an in-place pattern-defeating quicksort.
+//
+//	input: Slice to sort.
+//	compare: Comparator function.
+//
+// Uses PartitionFat, so that the runs of equal values are settled
+// at once, and falls back to SortHeap on the adversarial inputs.
+// The sort isn't stable, see SortStable.
+func SortPdq[T any](input []T, compare Compare[T]) {
+	sortPdq(input, compare, bits.Len(uint(len(input))))
+}
+
+// SortStable is a stable merge sort.
+//
+//	input: Slice to sort.
+//	compare: Comparator function.
+//
+// Allocates a scratch slice of the input length.
+func SortStable[T any](input []T, compare Compare[T]) {
+	n := len(input)
+	if n <= sortInsertionThreshold {
+		sortInsertion(input, compare)
+		return
+	}
+
+	for lo := 0; lo < n; lo += sortInsertionThreshold {
+		hi := lo + sortInsertionThreshold
+		if hi > n {
+			hi = n
+		}
+		sortInsertion(input[lo:hi], compare)
+	}
+
+	// Merging the sorted runs back and forth between the slices.
+	src, dst := input, make([]T, n)
+	for width := sortInsertionThreshold; width < n; width <<= 1 {
+		for lo := 0; lo < n; lo += width << 1 {
+			mid, hi := lo+width, lo+width<<1
+			if mid > n {
+				mid = n
+			}
+			if hi > n {
+				hi = n
+			}
+			sortMerge(dst[lo:hi], src[lo:mid], src[mid:hi], compare)
+		}
+		src, dst = dst, src
+	}
+
+	if &src[0] != &input[0] {
+		copy(input, src)
+	}
+}
+
+func sortPdq[T any](x []T, compare Compare[T], limit int) {
+	for len(x) > sortInsertionThreshold {
+		// Too many bad partitions, guaranteeing O(n*log(n)).
+		if limit == 0 {
+			SortHeap(x, compare)
+			return
+		}
+
+		less, greater := PartitionFat(x, sortPivot(x, compare), compare)
+		left, right := x[:less], x[greater+1:]
+
+		// Unbalanced partition, the input might be crafted.
+		if len(left) < len(x)>>3 || len(right) < len(x)>>3 {
+			limit--
+			sortBreakPatterns(left)
+			sortBreakPatterns(right)
+		}
+
+		// Recursing into the smaller part bounds the stack depth.
+		if len(left) < len(right) {
+			sortPdq(left, compare, limit)
+			x = right
+		} else {
+			sortPdq(right, compare, limit)
+			x = left
+		}
+	}
+	sortInsertion(x, compare)
+}
+
+// sortPivot picks the median of three, or the Tukey's ninther
+// for the longer slices.
+func sortPivot[T any](x []T, compare Compare[T]) T {
+	n := len(x)
+	a, b, c := n>>2, n>>1, 3*(n>>2)
+	if n >= 50 {
+		step := n >> 3
+		a = sortMedian(x, compare, a-step, a, a+step)
+		b = sortMedian(x, compare, b-step, b, b+step)
+		c = sortMedian(x, compare, c-step, c, c+step)
+	}
+	return x[sortMedian(x, compare, a, b, c)]
+}
+
+// sortMedian returns the index of the median of x[a], x[b] and x[c].
+func sortMedian[T any](x []T, compare Compare[T], a, b, c int) int {
+	if compare.Less(x[b], x[a]) {
+		a, b = b, a
+	}
+	if compare.Less(x[c], x[b]) {
+		b = c
+		if compare.Less(x[b], x[a]) {
+			b = a
+		}
+	}
+	return b
+}
+
+// sortBreakPatterns swaps a few elements around the middle
+// with the pseudo-random ones.
+func sortBreakPatterns[T any](x []T) {
+	n := len(x)
+	if n < 8 {
+		return
+	}
+
+	seed := uint(n)
+	mask := uint(1)<<bits.Len(uint(n)) - 1
+	for i := n>>1 - 1; i <= n>>1+1; i++ {
+		// Xorshift.
+		seed ^= seed << 13
+		seed ^= seed >> 7
+		seed ^= seed << 17
+
+		j := int(seed & mask)
+		if j >= n {
+			j -= n
+		}
+		SliceSwap(x, i, j)
+	}
+}
+
+func sortInsertion[T any](x []T, compare Compare[T]) {
+	for i := 1; i < len(x); i++ {
+		for j := i; j > 0 && compare.Less(x[j], x[j-1]); j-- {
+			SliceSwap(x, j, j-1)
+		}
+	}
+}
+
+// sortMerge merges the sorted a and b into dst, taking from a
+// on ties. The dst length must be len(a) + len(b).
+func sortMerge[T any](dst, a, b []T, compare Compare[T]) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if compare.Less(b[j], a[i]) {
+			dst[k] = b[j]
+			j++
+		} else {
+			dst[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
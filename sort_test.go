@@ -0,0 +1,134 @@
+package sly
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slices"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func FuzzSortPdq(f *testing.F) {
+	f.Add([]byte{100, 48, 3, 82, 56, 62, 71, 39, 42, 22})
+	f.Add([]byte(nil))
+	f.Fuzz(func(t *testing.T, bs []byte) {
+		h := append([]byte(nil), bs...)
+		sort.Slice(h, func(i, j int) bool {
+			return h[i] < h[j]
+		})
+
+		SortPdq(bs, CompareOrdered[byte])
+
+		for i := range bs {
+			if h[i] != bs[i] {
+				t.Fatalf("want: %v at %v, have: %v", h[i], i, bs[i])
+			}
+		}
+	})
+}
+
+func FuzzSortStable(f *testing.F) {
+	f.Add([]byte{100, 48, 3, 82, 56, 62, 71, 39, 42, 22, 3, 48, 48, 100})
+	f.Add([]byte(nil))
+	f.Fuzz(func(t *testing.T, bs []byte) {
+		type pair struct {
+			key   byte
+			index int
+		}
+		// Sorting by the high bits only, so that there are ties.
+		compare := func(a, b pair) int {
+			return CompareOrdered(a.key>>4, b.key>>4)
+		}
+
+		h := make([]pair, len(bs))
+		for i, b := range bs {
+			h[i] = pair{key: b, index: i}
+		}
+		hs := append([]pair(nil), h...)
+
+		sort.SliceStable(h, func(i, j int) bool {
+			return compare(h[i], h[j]) < 0
+		})
+
+		SortStable(hs, compare)
+
+		for i := range h {
+			if h[i] != hs[i] {
+				t.Fatalf("want: %v at %v, have: %v", h[i], i, hs[i])
+			}
+		}
+	})
+}
+
+func TestSortPdqPatterns(t *testing.T) {
+	const n = 10000
+	patterns := map[string]func(i int) int{
+		"sorted":   func(i int) int { return i },
+		"reversed": func(i int) int { return n - i },
+		"equal":    func(i int) int { return 0 },
+		"sawtooth": func(i int) int { return i % 100 },
+		"pipe": func(i int) int {
+			if i < n/2 {
+				return i
+			}
+			return n - i
+		},
+	}
+
+	for name, pattern := range patterns {
+		pattern := pattern
+		t.Run(name, func(t *testing.T) {
+			x := make([]int, n)
+			for i := range x {
+				x[i] = pattern(i)
+			}
+
+			SortPdq(x, CompareOrdered[int])
+			assert.True(t, sort.IntsAreSorted(x))
+		})
+	}
+}
+
+func BenchmarkSort(b *testing.B) {
+	for _, n := range []int{1 << 8, 1 << 16} {
+		random := make([]int, n)
+		for i := range random {
+			random[i] = rand.Intn(n)
+		}
+		x := make([]int, n)
+
+		b.Run(fmt.Sprintf("SortPdq/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(x, random)
+				SortPdq(x, CompareOrdered[int])
+			}
+		})
+		b.Run(fmt.Sprintf("SortStable/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(x, random)
+				SortStable(x, CompareOrdered[int])
+			}
+		})
+		b.Run(fmt.Sprintf("SortHeap/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(x, random)
+				SortHeap(x, CompareOrdered[int])
+			}
+		})
+		b.Run(fmt.Sprintf("sort.Slice/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(x, random)
+				sort.Slice(x, func(i, j int) bool {
+					return x[i] < x[j]
+				})
+			}
+		})
+		b.Run(fmt.Sprintf("slices.SortFunc/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(x, random)
+				slices.SortFunc(x, CompareOrdered[int])
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package sly
+
+// LowerBound searches the sorted slice for the first value
+// not less than v.
+//
+//	x: Sorted slice to search in.
+//	v: Value to search for.
+//	compare: Comparator function.
+//
+// Returns the index of the value found, or len(x) if there's none.
+func LowerBound[T any](x []T, v T, compare Compare[T]) int {
+	lo, hi := 0, len(x)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if compare.Less(x[mid], v) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// UpperBound searches the sorted slice for the first value
+// greater than v.
+//
+//	x: Sorted slice to search in.
+//	v: Value to search for.
+//	compare: Comparator function.
+//
+// Returns the index of the value found, or len(x) if there's none.
+func UpperBound[T any](x []T, v T, compare Compare[T]) int {
+	lo, hi := 0, len(x)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if compare.LessOrEqual(x[mid], v) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// EqualRange searches the sorted slice for the values equal to v.
+//
+//	x: Sorted slice to search in.
+//	v: Value to search for.
+//	compare: Comparator function.
+//
+// Returns a pair of indices:
+//
+//	lo: LowerBound(x, v, compare).
+//	hi: UpperBound(x, v, compare).
+//
+//	all(y == v for y in x[lo:hi])
+func EqualRange[T any](x []T, v T, compare Compare[T]) (lo, hi int) {
+	lo = LowerBound(x, v, compare)
+	hi = lo + UpperBound(x[lo:], v, compare)
+	return
+}
+
+// BinarySearch searches the sorted slice for v.
+//
+//	x: Sorted slice to search in.
+//	v: Value to search for.
+//	compare: Comparator function.
+//
+// Returns the index of the first value equal to v and true,
+// or the index v would be inserted at and false.
+func BinarySearch[T any](x []T, v T, compare Compare[T]) (int, bool) {
+	i := LowerBound(x, v, compare)
+	return i, i < len(x) && compare.Equal(x[i], v)
+}
+
+// SortedInsert inserts the value into the sorted slice,
+// after the equal ones.
+//
+//	slicePtr: Sorted slice pointer.
+//	v: Value to insert.
+//	compare: Comparator function.
+//
+// Returns the slice with the value inserted.
+func SortedInsert[T any](slicePtr *[]T, v T, compare Compare[T]) []T {
+	x := *slicePtr
+
+	i := UpperBound(x, v, compare)
+	var z T
+	x = append(x, z)
+	copy(x[i+1:], x[i:])
+	x[i] = v
+
+	*slicePtr = x
+	return x
+}
+
+// SortedRemove removes the first value equal to v from the sorted slice.
+//
+//	slicePtr: Sorted slice pointer.
+//	v: Value to remove.
+//	compare: Comparator function.
+//
+// Returns true if the value has been removed.
+func SortedRemove[T any](slicePtr *[]T, v T, compare Compare[T]) bool {
+	x := *slicePtr
+
+	i, ok := BinarySearch(x, v, compare)
+	if !ok {
+		return false
+	}
+	copy(x[i:], x[i+1:])
+	// Not retaining the removed value.
+	var z T
+	x[len(x)-1] = z
+	x = x[:len(x)-1]
+
+	*slicePtr = x
+	return true
+}
+
+// SortedMerge merges two sorted slices, taking from a on ties.
+//
+//	a: First sorted slice.
+//	b: Second sorted slice.
+//	compare: Comparator function.
+//
+// Returns the new sorted slice.
+func SortedMerge[T any](a, b []T, compare Compare[T]) []T {
+	merged := make([]T, len(a)+len(b))
+	sortMerge(merged, a, b, compare)
+	return merged
+}
+
+// SortedUnion unites two sorted slices. Each value goes as many times
+// as it does in either slice at most, taking from a on ties.
+//
+//	a: First sorted slice.
+//	b: Second sorted slice.
+//	compare: Comparator function.
+//
+// Returns the new sorted slice.
+func SortedUnion[T any](a, b []T, compare Compare[T]) []T {
+	union := make([]T, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch cmp := compare(a[i], b[j]); {
+		case cmp < 0:
+			union = append(union, a[i])
+			i++
+		case cmp > 0:
+			union = append(union, b[j])
+			j++
+		default:
+			union = append(union, a[i])
+			i++
+			j++
+		}
+	}
+	union = append(union, a[i:]...)
+	return append(union, b[j:]...)
+}
+
+// SortedIntersect intersects two sorted slices. Each value goes as many
+// times as it does in both slices at least, taking from a.
+//
+//	a: First sorted slice.
+//	b: Second sorted slice.
+//	compare: Comparator function.
+//
+// Returns the new sorted slice.
+func SortedIntersect[T any](a, b []T, compare Compare[T]) []T {
+	var intersection []T
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch cmp := compare(a[i], b[j]); {
+		case cmp < 0:
+			i++
+		case cmp > 0:
+			j++
+		default:
+			intersection = append(intersection, a[i])
+			i++
+			j++
+		}
+	}
+	return intersection
+}
+
+// SortedDifference subtracts the sorted slice b from the sorted slice a.
+// Each value of b cancels out one equal value of a.
+//
+//	a: Sorted slice to subtract from.
+//	b: Sorted slice to subtract.
+//	compare: Comparator function.
+//
+// Returns the new sorted slice.
+func SortedDifference[T any](a, b []T, compare Compare[T]) []T {
+	var difference []T
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch cmp := compare(a[i], b[j]); {
+		case cmp < 0:
+			difference = append(difference, a[i])
+			i++
+		case cmp > 0:
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return append(difference, a[i:]...)
+}
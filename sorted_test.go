@@ -0,0 +1,99 @@
+package sly
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestBounds(t *testing.T) {
+	x := []int{1, 2, 2, 2, 4}
+
+	assert.Equal(t, 1, LowerBound(x, 2, CompareOrdered[int]))
+	assert.Equal(t, 4, UpperBound(x, 2, CompareOrdered[int]))
+	assert.Equal(t, 4, LowerBound(x, 3, CompareOrdered[int]))
+	assert.Equal(t, 4, UpperBound(x, 3, CompareOrdered[int]))
+	assert.Equal(t, 0, LowerBound(x, 0, CompareOrdered[int]))
+	assert.Equal(t, 5, UpperBound(x, 4, CompareOrdered[int]))
+	assert.Equal(t, 0, LowerBound(nil, 0, CompareOrdered[int]))
+
+	lo, hi := EqualRange(x, 2, CompareOrdered[int])
+	assert.Equal(t, 1, lo)
+	assert.Equal(t, 4, hi)
+
+	lo, hi = EqualRange(x, 3, CompareOrdered[int])
+	assert.Equal(t, 4, lo)
+	assert.Equal(t, 4, hi)
+}
+
+func TestBinarySearch(t *testing.T) {
+	x := []int{1, 3, 3, 5}
+
+	i, ok := BinarySearch(x, 3, CompareOrdered[int])
+	assert.Equal(t, 1, i)
+	assert.True(t, ok)
+
+	i, ok = BinarySearch(x, 4, CompareOrdered[int])
+	assert.Equal(t, 3, i)
+	assert.False(t, ok)
+
+	i, ok = BinarySearch(x, 6, CompareOrdered[int])
+	assert.Equal(t, 4, i)
+	assert.False(t, ok)
+}
+
+func FuzzSortedInsertRemove(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49})
+	f.Add([]byte(nil))
+	f.Fuzz(func(t *testing.T, bs []byte) {
+		var x []byte
+		for _, b := range bs {
+			SortedInsert(&x, b, CompareOrdered[byte])
+		}
+
+		want := append([]byte(nil), bs...)
+		sort.Slice(want, func(i, j int) bool {
+			return want[i] < want[j]
+		})
+		assert.Equal(t, want, x)
+
+		for _, b := range bs {
+			assert.True(t, SortedRemove(&x, b, CompareOrdered[byte]))
+			assert.True(t, sort.SliceIsSorted(x, func(i, j int) bool {
+				return x[i] < x[j]
+			}))
+		}
+		assert.Empty(t, x)
+		assert.False(t, SortedRemove(&x, 0, CompareOrdered[byte]))
+	})
+}
+
+func TestSortedSetOperations(t *testing.T) {
+	a := []int{1, 2, 2, 3, 5}
+	b := []int{2, 3, 3, 4}
+
+	assert.Equal(t, []int{1, 2, 2, 2, 3, 3, 3, 4, 5}, SortedMerge(a, b, CompareOrdered[int]))
+	assert.Equal(t, []int{1, 2, 2, 3, 3, 4, 5}, SortedUnion(a, b, CompareOrdered[int]))
+	assert.Equal(t, []int{2, 3}, SortedIntersect(a, b, CompareOrdered[int]))
+	assert.Equal(t, []int{1, 2, 5}, SortedDifference(a, b, CompareOrdered[int]))
+
+	assert.Empty(t, SortedMerge(nil, nil, CompareOrdered[int]))
+	assert.Equal(t, a, SortedUnion(a, nil, CompareOrdered[int]))
+	assert.Empty(t, SortedIntersect(a, nil, CompareOrdered[int]))
+	assert.Equal(t, a, SortedDifference(a, nil, CompareOrdered[int]))
+}
+
+func TestSortedMergeStable(t *testing.T) {
+	type pair struct {
+		key  int
+		from string
+	}
+	compare := CompareBy(func(p pair) int { return p.key }, CompareOrdered[int])
+
+	merged := SortedMerge(
+		[]pair{{1, "a"}, {2, "a"}},
+		[]pair{{1, "b"}, {2, "b"}},
+		compare,
+	)
+	assert.Equal(t, []pair{{1, "a"}, {1, "b"}, {2, "a"}, {2, "b"}}, merged)
+}
@@ -0,0 +1,86 @@
+package sly
+
+import "context"
+
+// TopK collects the k highest priority values out of the offered ones.
+//
+// TopK isn't thread-safe.
+type TopK[T any] struct {
+	// Reversed heap, the lowest priority value is on top.
+	heap    []T
+	k       int
+	compare Compare[T]
+	reverse Compare[T]
+}
+
+// NewTopK creates a new top-k collector.
+//
+//	k: Number of values to keep.
+//	compare: Comparator function.
+func NewTopK[T any](k uint, compare Compare[T]) *TopK[T] {
+	return &TopK[T]{
+		heap:    make([]T, 0, k),
+		k:       int(k),
+		compare: compare,
+		reverse: CompareReverse(compare),
+	}
+}
+
+// Offer offers the value to the collector, evicting the lowest
+// priority one if there are k values already.
+//
+//	x: Value to offer.
+//
+// Returns true if the value has been kept.
+func (tk *TopK[T]) Offer(x T) bool {
+	if len(tk.heap) < tk.k {
+		HeapPush(&tk.heap, x, tk.reverse)
+		return true
+	}
+
+	if tk.k == 0 || tk.compare.LessOrEqual(x, tk.heap[0]) {
+		return false
+	}
+	tk.heap[0] = x
+	heapSiftDown(tk.heap, tk.reverse, SliceSwap[T], 0)
+	return true
+}
+
+// RelayFrom offers the values read from the source channel.
+//
+//	ctx: Cancellation context. If nil, defaults to context.Background().
+//	source: Channel to read from.
+//
+// Blocks until the source is closed or the context is done.
+func (tk *TopK[T]) RelayFrom(ctx context.Context, source <-chan T) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		select {
+		case value, more := <-source:
+			if !more {
+				return
+			}
+			tk.Offer(value)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Len returns the number of values kept.
+func (tk *TopK[T]) Len() int {
+	return len(tk.heap)
+}
+
+// Result copies the values kept.
+//
+// Returns the values in priority order.
+func (tk *TopK[T]) Result() []T {
+	values := append([]T(nil), tk.heap...)
+	SortHeap(values, tk.reverse)
+	return values
+}
@@ -0,0 +1,76 @@
+package sly
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		tk := NewTopK(3, CompareOrdered[int])
+		assert.Empty(t, tk.Result())
+
+		assert.True(t, tk.Offer(1))
+		assert.True(t, tk.Offer(5))
+		assert.Equal(t, []int{5, 1}, tk.Result())
+
+		assert.True(t, tk.Offer(3))
+		assert.True(t, tk.Offer(4))
+		assert.False(t, tk.Offer(2))
+		assert.False(t, tk.Offer(3))
+		assert.Equal(t, 3, tk.Len())
+		assert.Equal(t, []int{5, 4, 3}, tk.Result())
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		tk := NewTopK(0, CompareOrdered[int])
+		assert.False(t, tk.Offer(1))
+		assert.Empty(t, tk.Result())
+	})
+
+	t.Run("relay", func(t *testing.T) {
+		tk := NewTopK(2, CompareReverse(CompareOrdered[int]))
+		tk.RelayFrom(nil, ChanStream(nil, 0, 4, 2, 3, 1))
+		assert.Equal(t, []int{1, 2}, tk.Result())
+	})
+
+	t.Run("relay cancel", func(t *testing.T) {
+		tk := NewTopK(2, CompareOrdered[int])
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		// Never closed.
+		tk.RelayFrom(ctx, make(chan int))
+	})
+}
+
+func FuzzTopK(f *testing.F) {
+	f.Add([]byte{87, 40, 12, 20, 33, 20, 31, 11, 3, 49}, uint8(4))
+	f.Add([]byte(nil), uint8(1))
+	f.Fuzz(func(t *testing.T, bs []byte, k uint8) {
+		tk := NewTopK(uint(k), CompareOrdered[byte])
+		for _, b := range bs {
+			tk.Offer(b)
+		}
+
+		want := append([]byte(nil), bs...)
+		sort.Slice(want, func(i, j int) bool {
+			return want[i] > want[j]
+		})
+		if len(want) > int(k) {
+			want = want[:k]
+		}
+
+		have := tk.Result()
+		if len(have) != len(want) {
+			t.Fatalf("want: %v, have: %v", want, have)
+		}
+		for i := range want {
+			if have[i] != want[i] {
+				t.Fatalf("want: %v at %v, have: %v", want[i], i, have[i])
+			}
+		}
+	})
+}